@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by CircuitBreaker.Execute without calling fn while the circuit is open.
+var ErrOpen = errors.New("circuit breaker: open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker stops calling a failing dependency for a while, so callers fail fast
+// instead of piling up on something that is down (e.g. publish in Rule 61).
+//
+// It starts closed, and calls fn every time. After threshold consecutive failures it opens,
+// and Execute returns ErrOpen for cooldown. Then it is half-open: a single trial call is let through,
+// which closes the circuit again if it succeeds, or reopens it for another cooldown if it fails.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// generation changes with every state change, so that a call that was let through
+	// before the change doesn't count towards the new state (see record).
+	generation uint64
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Execute calls fn unless the circuit is open, and returns its error.
+// fn runs without the lock held, so a slow call doesn't block the callers that are turned away.
+// A panic in fn counts as a failure, and goes on to the caller.
+func (b *CircuitBreaker) Execute(fn func() error) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			// Without recording it, a panicking trial call would leave the circuit half-open for good.
+			b.record(generation, false)
+			panic(r)
+		}
+	}()
+
+	err = fn()
+	b.record(generation, err == nil)
+	return err
+}
+
+// allow returns the generation the call is let through in, or ErrOpen.
+func (b *CircuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return 0, ErrOpen
+		}
+		// The caller that observes the end of the cooldown makes the trial call.
+		b.setState(circuitHalfOpen)
+		return b.generation, nil
+	case circuitHalfOpen:
+		// The trial call is still running.
+		return 0, ErrOpen
+	default:
+		return b.generation, nil
+	}
+}
+
+// record updates the circuit with the outcome of a call let through in generation.
+// A call let through before the last state change is ignored: a slow call that started
+// while closed and succeeds after the circuit opened must not close it again without a trial.
+func (b *CircuitBreaker) record(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	if success {
+		b.failures = 0
+		if b.state != circuitClosed {
+			b.setState(circuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.setState(circuitOpen)
+		b.openedAt = time.Now()
+	}
+}
+
+// setState must be called with b.mu held.
+func (b *CircuitBreaker) setState(state circuitState) {
+	b.state = state
+	b.generation++
+}
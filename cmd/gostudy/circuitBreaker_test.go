@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	errDown := errors.New("dependency is down")
+	breaker := NewCircuitBreaker(3, cooldown)
+
+	calls := 0
+	failing := func() error {
+		calls++
+		return errDown
+	}
+	succeeding := func() error {
+		calls++
+		return nil
+	}
+
+	// Closed: every call goes through until the threshold is reached.
+	for i := 0; i < 3; i++ {
+		if err := breaker.Execute(failing); !errors.Is(err, errDown) {
+			t.Fatalf("call %d: Execute() = %v, want %v", i, err, errDown)
+		}
+	}
+
+	// Open: calls are blocked without running fn.
+	if err := breaker.Execute(succeeding); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Execute() while open = %v, want %v", err, ErrOpen)
+	}
+	if calls != 3 {
+		t.Fatalf("fn called %d times, want 3", calls)
+	}
+
+	// Half-open: a failing trial call reopens the circuit for another cooldown.
+	time.Sleep(cooldown)
+	if err := breaker.Execute(failing); !errors.Is(err, errDown) {
+		t.Fatalf("trial Execute() = %v, want %v", err, errDown)
+	}
+	if err := breaker.Execute(succeeding); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Execute() after a failed trial = %v, want %v", err, ErrOpen)
+	}
+
+	// Half-open: a successful trial call closes the circuit.
+	time.Sleep(cooldown)
+	if err := breaker.Execute(succeeding); err != nil {
+		t.Fatalf("trial Execute() = %v, want nil", err)
+	}
+
+	// Closed again, with the failure count reset.
+	for i := 0; i < 2; i++ {
+		if err := breaker.Execute(failing); !errors.Is(err, errDown) {
+			t.Fatalf("Execute() after closing = %v, want %v", err, errDown)
+		}
+	}
+	if err := breaker.Execute(succeeding); err != nil {
+		t.Fatalf("Execute() below the threshold = %v, want nil", err)
+	}
+	if calls != 8 {
+		t.Fatalf("fn called %d times, want 8", calls)
+	}
+}
+
+func TestCircuitBreakerSingleTrialCall(t *testing.T) {
+	const cooldown = 10 * time.Millisecond
+	breaker := NewCircuitBreaker(1, cooldown)
+	breaker.Execute(func() error { return errors.New("failed") })
+	time.Sleep(cooldown)
+
+	const callers = 10
+	var trials, rejected atomic.Int64
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := breaker.Execute(func() error {
+				trials.Add(1)
+				<-release
+				return nil
+			})
+			if errors.Is(err, ErrOpen) {
+				rejected.Add(1)
+			}
+		}()
+	}
+	// The trial call only completes once every other caller has been turned away.
+	for deadline := time.Now().Add(2 * time.Second); rejected.Load() < callers-1; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers rejected while half-open, want %d", rejected.Load(), callers-1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if trials.Load() != 1 {
+		t.Fatalf("%d trial calls while half-open, want 1", trials.Load())
+	}
+}
+
+func TestCircuitBreakerPanickingTrialCall(t *testing.T) {
+	const cooldown = 10 * time.Millisecond
+	breaker := NewCircuitBreaker(1, cooldown)
+	breaker.Execute(func() error { return errors.New("failed") })
+	time.Sleep(cooldown)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "trial crashed" {
+				t.Fatalf("recovered %v, want the panic of fn", r)
+			}
+		}()
+		breaker.Execute(func() error { panic("trial crashed") })
+	}()
+
+	// The panic reopened the circuit, instead of leaving it half-open forever.
+	if err := breaker.Execute(func() error { return nil }); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Execute() after a panicking trial = %v, want %v", err, ErrOpen)
+	}
+	time.Sleep(cooldown)
+	if err := breaker.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("trial Execute() after the cooldown = %v, want nil", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleSuccess(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Hour)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := make(chan error)
+	go func() {
+		slow <- breaker.Execute(func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// The circuit opens while the slow call, let through when it was closed, is still running.
+	if err := breaker.Execute(func() error { return errors.New("failed") }); err == nil {
+		t.Fatal("failing Execute() = nil, want its error")
+	}
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatalf("slow Execute() = %v, want nil", err)
+	}
+
+	if err := breaker.Execute(func() error { return nil }); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Execute() after a stale success = %v, want %v", err, ErrOpen)
+	}
+}
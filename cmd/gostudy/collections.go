@@ -0,0 +1,370 @@
+package main
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type priorityItem[T any] struct {
+	value    T
+	priority int
+	seq      uint64
+}
+
+// priorityHeap implements heap.Interface. Items with the same priority pop in insertion order.
+type priorityHeap[T any] []priorityItem[T]
+
+func (h priorityHeap[T]) Len() int { return len(h) }
+
+func (h priorityHeap[T]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityHeap[T]) Push(x any) { *h = append(*h, x.(priorityItem[T])) }
+
+func (h *priorityHeap[T]) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = priorityItem[T]{} // don't keep a reference to the popped value
+	*h = old[:len(old)-1]
+	return item
+}
+
+// PriorityQueue pops the item with the highest priority first.
+// container/heap isn't safe for concurrent use by itself, so every operation holds the mutex.
+type PriorityQueue[T any] struct {
+	mu    sync.Mutex
+	items priorityHeap[T]
+	seq   uint64
+}
+
+func (q *PriorityQueue[T]) Push(item T, priority int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	heap.Push(&q.items, priorityItem[T]{value: item, priority: priority, seq: q.seq})
+	q.seq++
+}
+
+// Pop removes and returns the item with the highest priority, and false if the queue is empty.
+func (q *PriorityQueue[T]) Pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(&q.items).(priorityItem[T]).value, true
+}
+
+func (q *PriorityQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Set is a set of comparable values that is safe for concurrent use.
+// Lookups take the read lock, so many readers can run in parallel (see Rule 70).
+type Set[T comparable] struct {
+	mu    sync.RWMutex
+	items map[T]struct{}
+}
+
+func NewSet[T comparable]() *Set[T] {
+	return &Set[T]{items: make(map[T]struct{})}
+}
+
+// Add inserts v, and returns false if it was already present.
+// Checking and inserting under the same lock makes it usable for deduplication:
+// exactly one of the goroutines adding the same value gets true.
+func (s *Set[T]) Add(v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[v]; ok {
+		return false
+	}
+	s.items[v] = struct{}{}
+	return true
+}
+
+func (s *Set[T]) Contains(v T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.items[v]
+	return ok
+}
+
+func (s *Set[T]) Remove(v T) {
+	s.mu.Lock()
+	delete(s.items, v)
+	s.mu.Unlock()
+}
+
+func (s *Set[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
+}
+
+// LRU is a cache holding at most capacity entries, which evicts the least recently used entry
+// to make room for a new one. It is safe for concurrent use.
+// Get moves the entry to the front of the list, so unlike Set, even lookups need the write lock.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is the most recently used, every element holds an *lruEntry
+	entries  map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// NewLRU panics if capacity is less than 1, like make does with a negative length.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity < 1 {
+		panic("NewLRU: capacity must be at least 1")
+	}
+	return &LRU[K, V]{capacity: capacity, order: list.New(), entries: make(map[K]*list.Element)}
+}
+
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// Put inserts or updates the value of key, and marks it as the most recently used.
+func (c *LRU[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	if c.order.Len() == c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+}
+
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stack is a lock-free LIFO stack (a Treiber stack): the head is swapped with a compare-and-swap,
+// and a goroutine that loses the race retries with the new head instead of waiting on a lock.
+// The classic ABA problem of this algorithm can't happen in Go: a node is never reused
+// while another goroutine still holds a pointer to it, because the garbage collector keeps it alive.
+type Stack[T any] struct {
+	head atomic.Pointer[stackNode[T]]
+}
+
+type stackNode[T any] struct {
+	value T
+	next  *stackNode[T]
+}
+
+func (s *Stack[T]) Push(v T) {
+	node := &stackNode[T]{value: v}
+	for {
+		node.next = s.head.Load()
+		if s.head.CompareAndSwap(node.next, node) {
+			return
+		}
+	}
+}
+
+// Pop removes the most recently pushed value, and returns false if the stack is empty.
+func (s *Stack[T]) Pop() (T, bool) {
+	for {
+		head := s.head.Load()
+		if head == nil {
+			var zero T
+			return zero, false
+		}
+		if s.head.CompareAndSwap(head, head.next) {
+			return head.value, true
+		}
+	}
+}
+
+// OrderedMap is a map that is safe for concurrent use, and remembers the order in which its keys were first set.
+// Like LRU, it keeps the entries in a list, so deleting a key doesn't have to shift the others.
+type OrderedMap[K comparable, V any] struct {
+	mu      sync.RWMutex
+	order   *list.List // in insertion order, every element holds an *orderedEntry
+	entries map[K]*list.Element
+}
+
+type orderedEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{order: list.New(), entries: make(map[K]*list.Element)}
+}
+
+// Set inserts or updates the value of key. Updating a key keeps its position.
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		element.Value.(*orderedEntry[K, V]).value = value
+		return
+	}
+	m.entries[key] = m.order.PushBack(&orderedEntry[K, V]{key: key, value: value})
+}
+
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return element.Value.(*orderedEntry[K, V]).value, true
+}
+
+func (m *OrderedMap[K, V]) Delete(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.order.Remove(element)
+		delete(m.entries, key)
+	}
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.order.Len()
+}
+
+// Range calls fn for every entry in insertion order, until fn returns false.
+// The entries are copied under the read lock, and fn is called without holding it,
+// so fn can call the other methods of the map without deadlocking (see Rule 68).
+// Changes made while Range runs are not seen by the remaining calls.
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	m.mu.RLock()
+	entries := make([]orderedEntry[K, V], 0, m.order.Len())
+	for element := m.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, *element.Value.(*orderedEntry[K, V]))
+	}
+	m.mu.RUnlock()
+
+	for _, entry := range entries {
+		if !fn(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+// Queue is an unbounded FIFO queue that is safe for concurrent use.
+// Consumers that want to wait for an item sleep on a sync.Cond instead of polling.
+//
+// A sync.Cond is always used with its lock held: Wait atomically unlocks the mutex and suspends
+// the goroutine, and locks the mutex again before returning. Because another consumer may take the item
+// between the Signal and the wakeup, Wait must always be called in a loop that re-checks the condition.
+type Queue[T any] struct {
+	mu       sync.Mutex
+	nonEmpty *sync.Cond
+	items    []T
+}
+
+func NewQueue[T any]() *Queue[T] {
+	q := &Queue[T]{}
+	q.nonEmpty = sync.NewCond(&q.mu)
+	return q
+}
+
+// Enqueue appends v and wakes up one waiting consumer, as one item can only satisfy one of them.
+func (q *Queue[T]) Enqueue(v T) {
+	q.mu.Lock()
+	q.items = append(q.items, v)
+	q.mu.Unlock()
+	q.nonEmpty.Signal()
+}
+
+// Dequeue removes the oldest item without waiting, and returns false if the queue is empty.
+func (q *Queue[T]) Dequeue() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pop()
+}
+
+// BlockingDequeue removes the oldest item, waiting for one until ctx is cancelled.
+// A sync.Cond can't be selected on together with ctx.Done, so a cancellation broadcasts to wake up
+// every waiter, and each of them checks its own context. The broadcast takes the lock first,
+// so it can't slip in between a waiter checking ctx and starting to wait.
+func (q *Queue[T]) BlockingDequeue(ctx context.Context) (T, error) {
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.nonEmpty.Broadcast()
+	})
+	defer stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+		q.nonEmpty.Wait()
+	}
+	v, _ := q.pop()
+	return v, nil
+}
+
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// pop must be called with q.mu held.
+func (q *Queue[T]) pop() (T, bool) {
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	v := q.items[0]
+	// Clear the slot so the queue doesn't keep the item alive.
+	var zero T
+	q.items[0] = zero
+	q.items = q.items[1:]
+	return v, true
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPriorityQueuePopOrder(t *testing.T) {
+	var q PriorityQueue[string]
+	q.Push("low", 1)
+	q.Push("high", 10)
+	q.Push("medium", 5)
+	q.Push("high-2", 10)
+
+	for _, want := range []string{"high", "high-2", "medium", "low"} {
+		got, ok := q.Pop()
+		if !ok || got != want {
+			t.Fatalf("Pop() = %q, %v; want %q, true", got, ok, want)
+		}
+	}
+	if got, ok := q.Pop(); ok {
+		t.Fatalf("Pop() on an empty queue = %q, true; want false", got)
+	}
+}
+
+func TestPriorityQueueConcurrent(t *testing.T) {
+	var q PriorityQueue[int]
+
+	const goroutines = 8
+	const items = 500
+	popped := make([]int, goroutines)
+
+	wg := sync.WaitGroup{}
+	wg.Add(2 * goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < items; j++ {
+				q.Push(j, j%7)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < items; j++ {
+				if _, ok := q.Pop(); ok {
+					popped[i]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := q.Len()
+	for _, n := range popped {
+		total += n
+	}
+	if total != goroutines*items {
+		t.Fatalf("popped + remaining = %d, want %d", total, goroutines*items)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := NewSet[string]()
+
+	if !s.Add("a") {
+		t.Fatal("Add(\"a\") = false on an empty set, want true")
+	}
+	if s.Add("a") {
+		t.Fatal("Add(\"a\") = true for a present value, want false")
+	}
+	if !s.Contains("a") || s.Contains("b") {
+		t.Fatalf("Contains(\"a\") = %v, Contains(\"b\") = %v; want true, false", s.Contains("a"), s.Contains("b"))
+	}
+
+	s.Remove("a")
+	if s.Contains("a") || s.Len() != 0 {
+		t.Fatalf("after Remove: Contains(\"a\") = %v, Len() = %d; want false, 0", s.Contains("a"), s.Len())
+	}
+}
+
+func TestSetConcurrentAdd(t *testing.T) {
+	s := NewSet[int]()
+
+	const goroutines = 16
+	const distinct = 1000
+	var added atomic.Int64
+
+	wg := sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			// Every goroutine adds every value, starting at a different offset.
+			for j := 0; j < distinct; j++ {
+				if s.Add((i*37 + j) % distinct) {
+					added.Add(1)
+				}
+				s.Contains(j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Len() != distinct {
+		t.Fatalf("Len() = %d, want %d", s.Len(), distinct)
+	}
+	if added.Load() != distinct {
+		t.Fatalf("Add returned true %d times, want %d", added.Load(), distinct)
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	cache := NewLRU[string, int](2)
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+
+	// Reading "a" makes "b" the least recently used entry.
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v; want 1, true", v, ok)
+	}
+	cache.Put("c", 3)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("Get(b) found an entry that should have been evicted")
+	}
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v; want 1, true", v, ok)
+	}
+
+	// Updating "c" makes it the most recently used, so "a" is evicted next.
+	cache.Put("c", 30)
+	cache.Put("d", 4)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("Get(a) found an entry that should have been evicted")
+	}
+	if v, ok := cache.Get("c"); !ok || v != 30 {
+		t.Fatalf("Get(c) = %d, %v; want 30, true", v, ok)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", cache.Len())
+	}
+}
+
+func TestLRUConcurrent(t *testing.T) {
+	const goroutines = 8
+	const capacity = 16
+
+	cache := NewLRU[int, int](capacity)
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := (g*1000 + i) % 64
+				cache.Put(key, key*2)
+				if v, ok := cache.Get(key); ok && v != key*2 {
+					t.Errorf("Get(%d) = %d, want %d", key, v, key*2)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cache.Len() != capacity {
+		t.Fatalf("Len() = %d, want %d", cache.Len(), capacity)
+	}
+}
+
+func TestStack(t *testing.T) {
+	var s Stack[int]
+	for i := 1; i <= 3; i++ {
+		s.Push(i)
+	}
+	for want := 3; want >= 1; want-- {
+		if v, ok := s.Pop(); !ok || v != want {
+			t.Fatalf("Pop() = %d, %v; want %d, true", v, ok, want)
+		}
+	}
+	if v, ok := s.Pop(); ok {
+		t.Fatalf("Pop() on an empty stack = %d, true; want false", v)
+	}
+}
+
+func TestStackConcurrent(t *testing.T) {
+	const goroutines = 8
+	const pushesPerGoroutine = 10000
+
+	var s Stack[int]
+	var pops atomic.Int64
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < pushesPerGoroutine; i++ {
+				s.Push(i)
+				// Pop every other time, so the stack is sometimes empty and sometimes not.
+				if i%2 == 0 {
+					if _, ok := s.Pop(); ok {
+						pops.Add(1)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	remaining := 0
+	for {
+		if _, ok := s.Pop(); !ok {
+			break
+		}
+		remaining++
+	}
+	if pushes := goroutines * pushesPerGoroutine; pops.Load()+int64(remaining) != int64(pushes) {
+		t.Fatalf("%d pops + %d remaining, want %d pushes", pops.Load(), remaining, pushes)
+	}
+}
+
+// mutexStack is the locked counterpart of Stack for BenchmarkMutexStack.
+type mutexStack[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+func (s *mutexStack[T]) Push(v T) {
+	s.mu.Lock()
+	s.items = append(s.items, v)
+	s.mu.Unlock()
+}
+
+func (s *mutexStack[T]) Pop() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	v := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return v, true
+}
+
+// BenchmarkStack and BenchmarkMutexStack push and pop from all goroutines at once.
+// Stack allocates a node for every push, while the slice of mutexStack is reused,
+// so lock-free is not automatically faster: with few CPUs, the allocation can cost more than the lock.
+func BenchmarkStack(b *testing.B) {
+	var s Stack[int64]
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Push(1)
+			v, _ := s.Pop()
+			Consume(v)
+		}
+	})
+}
+
+func BenchmarkMutexStack(b *testing.B) {
+	var s mutexStack[int64]
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Push(1)
+			v, _ := s.Pop()
+			Consume(v)
+		}
+	})
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	for i, key := range []string{"c", "a", "d", "b", "e"} {
+		m.Set(key, i)
+	}
+	m.Delete("d")
+	m.Delete("missing")
+	m.Set("a", 10) // an update keeps the position
+	m.Set("d", 20) // a deleted key is inserted again at the end
+
+	var keys []string
+	var values []int
+	m.Range(func(key string, value int) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		// The map can be used from fn, as Range doesn't hold the lock while calling it.
+		if _, ok := m.Get(key); !ok {
+			t.Fatalf("Get(%q) from Range failed", key)
+		}
+		return true
+	})
+
+	if want := []string{"c", "a", "b", "e", "d"}; !slices.Equal(keys, want) {
+		t.Fatalf("Range() keys = %v, want %v", keys, want)
+	}
+	if want := []int{0, 10, 3, 4, 20}; !slices.Equal(values, want) {
+		t.Fatalf("Range() values = %v, want %v", values, want)
+	}
+	if v, ok := m.Get("d"); !ok || v != 20 {
+		t.Fatalf("Get(d) = %d, %v; want 20, true", v, ok)
+	}
+
+	calls := 0
+	m.Range(func(string, int) bool {
+		calls++
+		return calls < 2
+	})
+	if calls != 2 {
+		t.Fatalf("Range() called fn %d times after it returned false, want 2", calls)
+	}
+}
+
+func TestOrderedMapConcurrent(t *testing.T) {
+	const goroutines = 8
+	m := NewOrderedMap[int, int]()
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := g*1000 + i
+				m.Set(key, i)
+				if i%2 == 1 {
+					m.Delete(key)
+				}
+				if i%100 == 0 {
+					m.Range(func(int, int) bool { return true })
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if m.Len() != goroutines*500 {
+		t.Fatalf("Len() = %d, want %d", m.Len(), goroutines*500)
+	}
+	// Every goroutine inserted its keys in increasing order, so that order survives in the map.
+	last := make(map[int]int)
+	m.Range(func(key, _ int) bool {
+		g := key / 1000
+		if previous, ok := last[g]; ok && key < previous {
+			t.Fatalf("key %d came after %d", key, previous)
+		}
+		last[g] = key
+		return true
+	})
+}
+
+func TestQueue(t *testing.T) {
+	q := NewQueue[int]()
+	if _, ok := q.Dequeue(); ok {
+		t.Fatal("Dequeue() on an empty queue returned an item")
+	}
+
+	for i := 1; i <= 3; i++ {
+		q.Enqueue(i)
+	}
+	for want := 1; want <= 3; want++ {
+		if v, ok := q.Dequeue(); !ok || v != want {
+			t.Fatalf("Dequeue() = %d, %v; want %d, true", v, ok, want)
+		}
+	}
+}
+
+func TestQueueProducersConsumers(t *testing.T) {
+	const producers = 4
+	const consumers = 4
+	const itemsPerProducer = 1000
+
+	q := NewQueue[int]()
+	var sum atomic.Int64
+	var received atomic.Int64
+
+	var consumersDone sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	for range consumers {
+		consumersDone.Add(1)
+		go func() {
+			defer consumersDone.Done()
+			for {
+				v, err := q.BlockingDequeue(ctx)
+				if err != nil {
+					return
+				}
+				sum.Add(int64(v))
+				received.Add(1)
+			}
+		}()
+	}
+
+	var producersDone sync.WaitGroup
+	for range producers {
+		producersDone.Add(1)
+		go func() {
+			defer producersDone.Done()
+			for i := 1; i <= itemsPerProducer; i++ {
+				q.Enqueue(i)
+			}
+		}()
+	}
+	producersDone.Wait()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for received.Load() < producers*itemsPerProducer && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	consumersDone.Wait()
+
+	if received.Load() != producers*itemsPerProducer {
+		t.Fatalf("received %d items, want %d", received.Load(), producers*itemsPerProducer)
+	}
+	if want := int64(producers * itemsPerProducer * (itemsPerProducer + 1) / 2); sum.Load() != want {
+		t.Fatalf("sum of received items = %d, want %d", sum.Load(), want)
+	}
+}
+
+func TestQueueBlockingDequeueCancelled(t *testing.T) {
+	q := NewQueue[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if v, err := q.BlockingDequeue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("BlockingDequeue() = %d, %v; want %v", v, err, context.DeadlineExceeded)
+	}
+
+	// An item that is already there is returned even if the context is cancelled.
+	q.Enqueue(7)
+	if v, err := q.BlockingDequeue(ctx); err != nil || v != 7 {
+		t.Fatalf("BlockingDequeue() = %d, %v; want 7, nil", v, err)
+	}
+}
@@ -0,0 +1,861 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//===============================================
+// Rule 61 Propagating an inappropriate context
+//===============================================
+
+// taskDuration is how long doSomeTask pretends to work.
+const taskDuration = 10 * time.Millisecond
+
+// doSomeTask simulates work that stops as soon as ctx is cancelled, e.g. when the client goes away.
+func doSomeTask(ctx context.Context, r *http.Request) (string, error) {
+	if err := SleepCtx(ctx, taskDuration); err != nil {
+		return "", err
+	}
+	return r.URL.Query().Get("id"), nil
+}
+
+// writeTaskError reports a failed doSomeTask to the client.
+// If the request context was cancelled, the client is gone, so there is nobody to write a response to.
+func writeTaskError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Printf("task abandoned: %v", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// publishDuration is how long publish takes to deliver a response, e.g. to a message broker.
+const publishDuration = 5 * time.Millisecond
+
+// publish simulates sending response to a broker, and gives up as soon as ctx is cancelled.
+// It runs in the background after the handler returned, so nobody sees its result but the debug logger.
+func publish(ctx context.Context, response string) error {
+	if err := SleepCtx(ctx, publishDuration); err != nil {
+		logger().Debug("publish cancelled")
+		return err
+	}
+	logger().Debug("published")
+	return nil
+}
+
+func writeResponse(w http.ResponseWriter, response string) {
+	_, _ = w.Write([]byte(response))
+}
+
+// The context attached to the request is cancelled as soon as the handler returns.
+// So, the publish goroutine may observe a cancelled context depending on the timing,
+// and with a publish slower than writing the response, it always does.
+func publishHandler(w http.ResponseWriter, r *http.Request) {
+	response, err := doSomeTask(r.Context(), r)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	go func() {
+		if err := publish(r.Context(), response); err != nil {
+			log.Printf("publish failed: %v", err)
+		}
+	}()
+
+	writeResponse(w, response)
+}
+
+// context.WithoutCancel keeps the values of the parent context, but is never cancelled.
+// This feature is added from Go 1.21.
+func fixedPublishHandler(w http.ResponseWriter, r *http.Request) {
+	response, err := doSomeTask(r.Context(), r)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	go func() {
+		if err := publish(context.WithoutCancel(r.Context()), response); err != nil {
+			log.Printf("publish failed: %v", err)
+		}
+	}()
+
+	writeResponse(w, response)
+}
+
+// Context values should be keyed by an unexported type.
+// A plain string key such as "trace_id" can collide with keys of other packages,
+// while a value of traceIDKey can only be created inside this package.
+type traceIDKey struct{}
+
+// WithTraceID returns a copy of ctx carrying the given trace ID.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFrom returns the trace ID stored in ctx, and false if there is none.
+func TraceIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(traceIDKey{}).(string)
+	return id, ok
+}
+
+// Request-scoped values survive context.WithoutCancel, so the detached publish
+// goroutine still knows which request it belongs to.
+func tracedPublishHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := WithTraceID(r.Context(), r.Header.Get("X-Trace-Id"))
+
+	response, err := doSomeTask(ctx, r)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := publish(ctx, response); err != nil {
+			id, _ := TraceIDFrom(ctx)
+			log.Printf("publish failed (trace_id=%s): %v", id, err)
+		}
+	}()
+
+	writeResponse(w, response)
+}
+
+// avoid61 publishes with the context of a request that already ended, as fixedPublishHandler does.
+// The request context is cancelled, but the detached one isn't, so publish still succeeds.
+func avoid61() error {
+	ctx, cancel := context.WithCancel(WithTraceID(context.Background(), "avoid61"))
+	cancel()
+	return publish(context.WithoutCancel(ctx), "response")
+}
+
+//===============================================
+// Rule 62 Starting a goroutine without knowing when to stop it
+//===============================================
+
+const watchInterval = 100 * time.Millisecond
+
+// watcher watches an external configuration in its own goroutine.
+// If newWatcher only started the goroutine, nothing could ever stop it,
+// and the resources it holds would only be released when the whole program exits.
+// So, the watcher exposes close, and whoever creates it is responsible for calling it.
+type watcher struct {
+	ctx       context.Context
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func newWatcher() *watcher {
+	return NewWatcherCtx(context.Background())
+}
+
+// NewWatcherCtx creates a watcher that also stops when ctx is cancelled,
+// so a server shutdown can stop every watcher without calling close on each of them.
+func NewWatcherCtx(ctx context.Context) *watcher {
+	w := &watcher{
+		ctx:     ctx,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.watch()
+	return w
+}
+
+func (w *watcher) watch() {
+	defer close(w.stopped)
+	logger().Debug("watcher started")
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Reload the configuration here.
+		case <-w.ctx.Done():
+			if debugEnabled() {
+				logger().Debug("watcher stopped", slog.String("reason", "context done"), slog.Any("error", w.ctx.Err()))
+			}
+			return
+		case <-w.done:
+			if debugEnabled() {
+				logger().Debug("watcher stopped", slog.String("reason", "closed"))
+			}
+			return
+		}
+	}
+}
+
+// close stops the watch goroutine and waits until it has returned.
+// It is safe to call close more than once, and after the context was cancelled.
+func (w *watcher) close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	<-w.stopped
+}
+
+// RunLoop is the watch loop above as a reusable template.
+// It calls onTick every tick until ctx is cancelled, in which case it returns nil,
+// or until onTick fails, in which case it returns the error.
+// The ticker is stopped in both cases, so nothing outlives the loop.
+func RunLoop(ctx context.Context, tick time.Duration, onTick func(context.Context) error) error {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := onTick(ctx); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func watcherExample() {
+	w := newWatcher()
+	defer w.close()
+
+	// Run the application
+}
+
+// avoid62 starts a watcher and closes it, and reports whether its goroutine has returned by then.
+func avoid62() bool {
+	w := newWatcher()
+	w.close()
+	select {
+	case <-w.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+//===============================================
+// Rule 64 Expecting deterministic behavior using select and channels
+//===============================================
+
+// When several cases of a select are ready, one of them is chosen at random, not in source order.
+// The randomness prevents starvation, but it means that the disconnection may be handled
+// while messages are still waiting in the buffer.
+func listing(messageCh <-chan int, disconnectCh <-chan struct{}) int {
+	processed := 0
+	for {
+		select {
+		case <-messageCh:
+			processed++
+		case <-disconnectCh:
+			return processed
+		}
+	}
+}
+
+// On disconnection, drain what is left in messageCh before returning.
+// The inner select has a default case, so it returns as soon as the buffer is empty.
+func listingDrained(messageCh <-chan int, disconnectCh <-chan struct{}) int {
+	processed := 0
+	for {
+		select {
+		case <-messageCh:
+			processed++
+		case <-disconnectCh:
+			for {
+				select {
+				case <-messageCh:
+					processed++
+				default:
+					return processed
+				}
+			}
+		}
+	}
+}
+
+// runListing sends 10 messages and then disconnects, and returns how many messages were processed.
+func runListing(listen func(<-chan int, <-chan struct{}) int) int {
+	messageCh := make(chan int, 10)
+	disconnectCh := make(chan struct{})
+	processed := make(chan int)
+
+	go func() {
+		processed <- listen(messageCh, disconnectCh)
+	}()
+
+	for i := 0; i < 10; i++ {
+		messageCh <- i
+	}
+	disconnectCh <- struct{}{}
+	return <-processed
+}
+
+func mistake64() int {
+	return runListing(listing)
+}
+
+func avoid64() int {
+	return runListing(listingDrained)
+}
+
+//===============================================
+// Rule 66 Not using nil channels
+//===============================================
+
+// mergeSpinning merges two channels, but keeps selecting on a channel after it is closed.
+// A receive from a closed channel never blocks, so once ch1 is closed,
+// the loop spins on it, burning a CPU until ch2 is closed too.
+func mergeSpinning(ch1, ch2 <-chan int) <-chan int {
+	ch := make(chan int, 1)
+	ch1Closed := false
+	ch2Closed := false
+
+	go func() {
+		for {
+			select {
+			case v, open := <-ch1:
+				if !open {
+					ch1Closed = true
+					break
+				}
+				ch <- v
+			case v, open := <-ch2:
+				if !open {
+					ch2Closed = true
+					break
+				}
+				ch <- v
+			}
+
+			if ch1Closed && ch2Closed {
+				close(ch)
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// merge sets a channel to nil once it is closed. Receiving from a nil channel blocks forever,
+// so the select simply stops considering it.
+//
+// Setting the channel to nil can't lose a value: a closed channel only reports !open
+// once every buffered value has been received, so the last value is always forwarded first.
+func merge(ch1, ch2 <-chan int) <-chan int {
+	ch := make(chan int, 1)
+
+	go func() {
+		for ch1 != nil || ch2 != nil {
+			select {
+			case v, open := <-ch1:
+				if !open {
+					if debugEnabled() {
+						logger().Debug("merge disabled a closed channel", slog.Int("channel", 0))
+					}
+					ch1 = nil
+					break
+				}
+				ch <- v
+			case v, open := <-ch2:
+				if !open {
+					if debugEnabled() {
+						logger().Debug("merge disabled a closed channel", slog.Int("channel", 1))
+					}
+					ch2 = nil
+					break
+				}
+				ch <- v
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// mergeN applies the same idea to any number of channels.
+// reflect.Select ignores a case whose channel is the zero Value, which plays the role of nil.
+func mergeN(channels ...<-chan int) <-chan int {
+	ch := make(chan int, 1)
+
+	cases := make([]reflect.SelectCase, len(channels))
+	for i, c := range channels {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)}
+	}
+
+	go func() {
+		for open := len(channels); open > 0; {
+			i, v, ok := reflect.Select(cases)
+			if !ok {
+				if debugEnabled() {
+					logger().Debug("merge disabled a closed channel", slog.Int("channel", i), slog.Int("open", open-1))
+				}
+				cases[i].Chan = reflect.Value{}
+				open--
+				continue
+			}
+			ch <- int(v.Int())
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func produce(values ...int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for _, v := range values {
+			ch <- v
+		}
+	}()
+	return ch
+}
+
+func mistake66() int {
+	sum := 0
+	for v := range mergeSpinning(produce(1, 2, 3), produce(4, 5, 6)) {
+		sum += v
+	}
+	return sum
+}
+
+func avoid66() int {
+	sum := 0
+	for v := range merge(produce(1, 2, 3), produce(4, 5, 6)) {
+		sum += v
+	}
+	return sum
+}
+
+//===============================================
+// Rule 67 Being puzzled about channel size
+//===============================================
+
+// An unbuffered channel gives a synchronization guarantee: the sender knows the value was received.
+// A buffered channel only decouples the two sides, and its size should come from a reason
+// (e.g. the number of workers, or the size of a burst), not from a guess like 40.
+// When there is no obvious reason, measure.
+
+// TuneBufferSize runs produce and consume over a channel of every candidate buffer size,
+// and returns the fastest size together with the duration of every run.
+// produce must only send on the channel, TuneBufferSize closes it once produce returns.
+// consume must receive until the channel is closed.
+func TuneBufferSize(produce func(chan<- int), consume func(<-chan int), candidates []int) (int, map[int]time.Duration) {
+	best := -1
+	timings := make(map[int]time.Duration, len(candidates))
+
+	for _, size := range candidates {
+		ch := make(chan int, size)
+
+		start := time.Now()
+		go func() {
+			produce(ch)
+			close(ch)
+		}()
+		consume(ch)
+		timings[size] = time.Since(start)
+
+		if best < 0 || timings[size] < timings[best] {
+			best = size
+		}
+	}
+	return best, timings
+}
+
+// receiveBlocking sums the values of ch until it is closed.
+// While ch is empty, the goroutine is parked and uses no CPU until a sender wakes it up.
+func receiveBlocking(ch <-chan int) int64 {
+	var sum int64
+	for v := range ch {
+		sum += int64(v)
+	}
+	return sum
+}
+
+// receivePolling does the same with a select and a default case, and counts how often ch was empty.
+// Don't do this: a select with default never blocks, so while ch is empty the loop spins,
+// burning a whole CPU to find nothing, and taking it from the goroutines that would fill ch.
+// A default case is for doing something else when the channel isn't ready, not for waiting on it.
+func receivePolling(ch <-chan int) (int64, int) {
+	var sum int64
+	empty := 0
+	for {
+		select {
+		case v, open := <-ch:
+			if !open {
+				return sum, empty
+			}
+			sum += int64(v)
+		default:
+			empty++
+		}
+	}
+}
+
+// avoid67 sums 1 to 10 from an unbuffered channel with a blocking receive, instead of polling it.
+func avoid67() int64 {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 1; i <= 10; i++ {
+			ch <- i
+		}
+	}()
+	return receiveBlocking(ch)
+}
+
+//===============================================
+// Rule 69 Creating data races with append
+//===============================================
+
+// append writes into the backing array of its argument whenever it has spare capacity,
+// so goroutines appending to the same slice race, even when each keeps the slice it got back.
+// The usual fix is a mutex around every append, which makes every goroutine take the same lock for every element.
+// Accumulating into a slice per goroutine and merging once at the end takes the lock once per goroutine.
+
+// appendLocked appends perGoroutine values from each of goroutines goroutines to one shared slice under a mutex.
+func appendLocked(goroutines, perGoroutine int) []int {
+	var mu sync.Mutex
+	var values []int
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				mu.Lock()
+				values = append(values, g*perGoroutine+i)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return values
+}
+
+// appendLocal does the same with a slice per goroutine, merged under the mutex once each goroutine is done.
+// The local slices are presized, and so is the merged one, so the whole run only allocates goroutines+1 slices.
+func appendLocal(goroutines, perGoroutine int) []int {
+	var mu sync.Mutex
+	values := make([]int, 0, goroutines*perGoroutine)
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func() {
+			defer wg.Done()
+			local := make([]int, 0, perGoroutine)
+			for i := 0; i < perGoroutine; i++ {
+				local = append(local, g*perGoroutine+i)
+			}
+			mu.Lock()
+			values = append(values, local...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return values
+}
+
+// avoid69 appends 0 to 399 from 4 goroutines with appendLocal, and returns the merged values.
+func avoid69() []int {
+	return appendLocal(4, 100)
+}
+
+//===============================================
+// Rule 70 Using mutexes inaccurately with slices and maps
+//===============================================
+
+type balanceCache struct {
+	mu       sync.RWMutex
+	balances map[string]float64
+}
+
+func newBalanceCache() *balanceCache {
+	return &balanceCache{balances: make(map[string]float64)}
+}
+
+func (c *balanceCache) addBalance(id string, balance float64) {
+	c.mu.Lock()
+	c.balances[id] = balance
+	c.mu.Unlock()
+}
+
+// Assigning a map to a new variable doesn't copy it, both variables point to the same hash table.
+// So, the loop below reads the map without the lock, while addBalance may be writing to it.
+func (c *balanceCache) averageBalanceWrong() float64 {
+	c.mu.RLock()
+	balances := c.balances
+	c.mu.RUnlock()
+
+	sum := 0.
+	for _, balance := range balances {
+		sum += balance
+	}
+	return sum / float64(len(balances))
+}
+
+// Either hold the lock for the whole iteration, or take a deep copy under the lock if the work is heavy.
+func (c *balanceCache) averageBalance() float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sum := 0.
+	for _, balance := range c.balances {
+		sum += balance
+	}
+	return sum / float64(len(c.balances))
+}
+
+// The same applies to slices. Copying a slice header under a lock doesn't copy the backing array.
+
+func mistake70() {
+	c := newBalanceCache()
+	c.addBalance("init", 1)
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.addBalance(strconv.Itoa(i), float64(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = c.averageBalanceWrong()
+		}
+	}()
+	wg.Wait()
+}
+
+func avoid70() {
+	c := newBalanceCache()
+	c.addBalance("init", 1)
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.addBalance(strconv.Itoa(i), float64(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = c.averageBalance()
+		}
+	}()
+	wg.Wait()
+}
+
+// Ranging over a map while another goroutine writes to it isn't only a data race.
+// The runtime detects it on a best-effort basis and aborts the whole program with
+// "fatal error: concurrent map iteration and map write". This is not a panic, recover can't stop it.
+func mistake70b() int {
+	m := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		m[i] = i
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			m[i%1000] = i
+		}
+	}()
+
+	sum := 0
+	for i := 0; i < 10000; i++ {
+		for _, v := range m {
+			sum += v
+		}
+	}
+	close(stop)
+	<-done
+	return sum
+}
+
+// Taking a snapshot under the lock keeps the critical section short,
+// and the snapshot can be ranged over for as long as we want without blocking the writer.
+func avoid70b() int {
+	var mu sync.Mutex
+	m := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		m[i] = i
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			mu.Lock()
+			m[i%1000] = i
+			mu.Unlock()
+		}
+	}()
+
+	sum := 0
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		snapshot := maps.Clone(m)
+		mu.Unlock()
+
+		for _, v := range snapshot {
+			sum += v
+		}
+	}
+	close(stop)
+	<-done
+	return sum
+}
+
+//===============================================
+// Rule 71 Misusing sync.WaitGroup
+//===============================================
+
+// wgGoroutines is the number of goroutines the Rule 71 examples wait for.
+const wgGoroutines = 3
+
+// mistake71 calls wg.Add inside the goroutines. Nothing guarantees that they run before wg.Wait,
+// and if none of them has called Add yet, the counter is zero and Wait returns right away.
+// It returns how many goroutines had finished when Wait returned, which is often fewer than wgGoroutines.
+// The race detector reports the Add racing with the Wait.
+//
+// go vet reports a wg.Add written at the start of a go func literal, so the Add is in the function
+// the goroutine calls here, as it usually is in real code, where vet can't see it.
+func mistake71() int {
+	var wg sync.WaitGroup
+	var finished atomic.Int64
+	for i := 0; i < wgGoroutines; i++ {
+		go countFinished(&wg, &finished)
+	}
+	wg.Wait()
+	return int(finished.Load())
+}
+
+func countFinished(wg *sync.WaitGroup, finished *atomic.Int64) {
+	wg.Add(1)
+	defer wg.Done()
+	finished.Add(1)
+}
+
+// Add must happen before the go statement, so the counter covers the goroutine before Wait can see it.
+// Adding the whole count up front, or 1 right before every go statement, are both fine.
+func avoid71() int {
+	var wg sync.WaitGroup
+	var finished atomic.Int64
+	wg.Add(wgGoroutines)
+	for i := 0; i < wgGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			finished.Add(1)
+		}()
+	}
+	wg.Wait()
+	return int(finished.Load())
+}
+
+// RunAllSafeExamples runs every avoid example of this file, for Rules 61, 62, 64, 66, 67, 69, 70 and 71,
+// and returns an error for each one that doesn't produce the expected result. Most of them share memory between goroutines,
+// so running it with the race detector (go run -race ./cmd/gostudy -self-test) also checks
+// that the correct versions stay free of data races.
+func RunAllSafeExamples() error {
+	var errs []error
+	for _, example := range []struct {
+		name string
+		run  func() error
+	}{
+		{"avoid61", avoid61},
+		{"avoid62", func() error {
+			if !avoid62() {
+				return errors.New("the watcher goroutine is still running after close")
+			}
+			return nil
+		}},
+		{"avoid64", func() error {
+			if processed := avoid64(); processed != 10 {
+				return fmt.Errorf("processed %d messages, want 10", processed)
+			}
+			return nil
+		}},
+		{"avoid66", func() error {
+			if sum := avoid66(); sum != 21 {
+				return fmt.Errorf("sum = %d, want 21", sum)
+			}
+			return nil
+		}},
+		{"avoid67", func() error {
+			if sum := avoid67(); sum != 55 {
+				return fmt.Errorf("sum = %d, want 55", sum)
+			}
+			return nil
+		}},
+		{"avoid69", func() error {
+			values := avoid69()
+			slices.Sort(values)
+			if len(values) != 400 {
+				return fmt.Errorf("got %d values, want 400", len(values))
+			}
+			for i, v := range values {
+				if v != i {
+					return fmt.Errorf("value %d = %d, want every value from 0 to 399 once", i, v)
+				}
+			}
+			return nil
+		}},
+		{"avoid70", func() error {
+			avoid70()
+			return nil
+		}},
+		{"avoid70b", func() error {
+			avoid70b()
+			return nil
+		}},
+		{"avoid71", func() error {
+			if finished := avoid71(); finished != wgGoroutines {
+				return fmt.Errorf("%d goroutines finished, want %d", finished, wgGoroutines)
+			}
+			return nil
+		}},
+	} {
+		if err := example.run(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", example.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoSomeTask(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?id=42", nil)
+
+	if response, err := doSomeTask(context.Background(), r); err != nil || response != "42" {
+		t.Fatalf("doSomeTask() = %q, %v; want %q, nil", response, err, "42")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(taskDuration/5, cancel)
+	start := time.Now()
+
+	if _, err := doSomeTask(ctx, r); !errors.Is(err, context.Canceled) {
+		t.Fatalf("doSomeTask() = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed >= taskDuration {
+		t.Fatalf("doSomeTask() returned after %v, want it to stop before the task completes", elapsed)
+	}
+}
+
+func TestPublishHandlerPropagatesTaskError(t *testing.T) {
+	for _, testcase := range []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"publishHandler", publishHandler},
+		{"fixedPublishHandler", fixedPublishHandler},
+		{"tracedPublishHandler", tracedPublishHandler},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			baseline := runtime.NumGoroutine()
+			recorder := httptest.NewRecorder()
+			testcase.handler(recorder, httptest.NewRequest(http.MethodGet, "/?id=42", nil))
+			if recorder.Code != http.StatusOK || recorder.Body.String() != "42" {
+				t.Fatalf("response = %d %q, want %d %q", recorder.Code, recorder.Body.String(), http.StatusOK, "42")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), taskDuration/5)
+			defer cancel()
+			recorder = httptest.NewRecorder()
+			testcase.handler(recorder, httptest.NewRequest(http.MethodGet, "/?id=42", nil).WithContext(ctx))
+			if recorder.Code != http.StatusGatewayTimeout {
+				t.Fatalf("status = %d after the deadline, want %d", recorder.Code, http.StatusGatewayTimeout)
+			}
+			// The publish goroutine of the first request outlives the handler.
+			waitForGoroutines(t, baseline)
+		})
+	}
+}
+
+// TestPublishAfterResponse serves the handlers over a real connection,
+// as the server cancels the request context when the handler returns, while httptest.NewRecorder doesn't.
+func TestPublishAfterResponse(t *testing.T) {
+	for _, testcase := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"publishHandler", publishHandler, "publish cancelled"},
+		{"fixedPublishHandler", fixedPublishHandler, "published"},
+		{"tracedPublishHandler", tracedPublishHandler, "published"},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			events := recordDebugEvents(t)
+			server := httptest.NewServer(testcase.handler)
+			defer server.Close()
+
+			response, err := http.Get(server.URL + "/?id=42")
+			if err != nil {
+				t.Fatalf("GET failed: %v", err)
+			}
+			response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", response.StatusCode, http.StatusOK)
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for events.count("published")+events.count("publish cancelled") == 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("publish did not finish")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if n := events.count(testcase.want); n != 1 {
+				t.Fatalf("logged %q %d times, want once", testcase.want, n)
+			}
+		})
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	ctx := WithTraceID(context.Background(), "abc-123")
+
+	id, ok := TraceIDFrom(ctx)
+	if !ok || id != "abc-123" {
+		t.Fatalf("TraceIDFrom() = %q, %v; want %q, true", id, ok, "abc-123")
+	}
+
+	// A string key with the same name must not collide with the private key type.
+	//nolint:staticcheck // intentionally using a string key to prove there is no collision
+	ctx = context.WithValue(ctx, "traceIDKey", "other")
+	if id, _ := TraceIDFrom(ctx); id != "abc-123" {
+		t.Fatalf("TraceIDFrom() = %q after string-keyed value; want %q", id, "abc-123")
+	}
+
+	// Values are kept when the context is detached from cancellation.
+	if id, ok := TraceIDFrom(context.WithoutCancel(ctx)); !ok || id != "abc-123" {
+		t.Fatalf("TraceIDFrom(WithoutCancel) = %q, %v; want %q, true", id, ok, "abc-123")
+	}
+}
+
+func TestTraceIDMissing(t *testing.T) {
+	id, ok := TraceIDFrom(context.Background())
+	if ok || id != "" {
+		t.Fatalf("TraceIDFrom() = %q, %v; want \"\", false", id, ok)
+	}
+}
+
+func TestWatcherClose(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	w := newWatcher()
+	w.close()
+	w.close()
+
+	waitForGoroutines(t, baseline)
+}
+
+func TestNewWatcherCtxStopsOnCancel(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := NewWatcherCtx(ctx)
+	cancel()
+
+	select {
+	case <-w.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("watch goroutine did not exit after the context was cancelled")
+	}
+	waitForGoroutines(t, baseline)
+
+	// close is still safe to call once the goroutine has already exited.
+	w.close()
+}
+
+func TestAppendLockedAndLocalAgree(t *testing.T) {
+	const goroutines, perGoroutine = 8, 1000
+
+	locked := appendLocked(goroutines, perGoroutine)
+	local := appendLocal(goroutines, perGoroutine)
+
+	if len(locked) != goroutines*perGoroutine || len(local) != goroutines*perGoroutine {
+		t.Fatalf("appendLocked() has %d values, appendLocal() %d; want %d", len(locked), len(local), goroutines*perGoroutine)
+	}
+	// The goroutines interleave differently, but every value must be there exactly once.
+	slices.Sort(locked)
+	slices.Sort(local)
+	for i := range locked {
+		if locked[i] != i || local[i] != i {
+			t.Fatalf("value %d: appendLocked() has %d, appendLocal() %d", i, locked[i], local[i])
+		}
+	}
+}
+
+// BenchmarkAppendLocked and BenchmarkAppendLocal append 1000 values from each of 64 goroutines.
+// On a single CPU the lock is rarely contended, yet appendLocked is about 5x slower (2ms against 0.4ms):
+// it pays for a lock and an unlock per value, and grows the shared slice from scratch, copying 2.5MB against 1MB.
+// appendLocal allocates more often, once per local slice, but each allocation is made once at the right size.
+// With more CPUs, contention on the shared lock widens the gap further.
+func BenchmarkAppendLocked(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(appendLocked(64, 1000))))
+	}
+}
+
+func BenchmarkAppendLocal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(appendLocal(64, 1000))))
+	}
+}
+
+func TestAvoid70(t *testing.T) {
+	avoid70()
+	avoid70b()
+}
+
+// TestRunAllSafeExamples is meant to be run with -race, which fails it if an example has a data race.
+func TestRunAllSafeExamples(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	if err := RunAllSafeExamples(); err != nil {
+		t.Fatalf("RunAllSafeExamples() = %v", err)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+// mistake70b crashes the process it runs in, so it runs in a child process of the test binary.
+// The runtime can only catch the iteration and the write overlapping when they run in parallel,
+// so the test is skipped when the crash doesn't happen, e.g. with a single CPU.
+// The child is killed after a timeout in case the runtime doesn't detect the concurrent access.
+func TestMistake70bCrashes(t *testing.T) {
+	if os.Getenv("GOSTUDY_RUN_MISTAKE70B") == "1" {
+		mistake70b()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestMistake70bCrashes$")
+	cmd.Env = append(os.Environ(), "GOSTUDY_RUN_MISTAKE70B=1")
+	output, err := cmd.CombinedOutput()
+
+	if err == nil {
+		t.Skip("the runtime did not detect the concurrent map access this time")
+	}
+	if ctx.Err() != nil {
+		t.Skip("mistake70b did not finish before the timeout")
+	}
+	// Under -race, the race detector usually reports the access before the runtime does.
+	if !strings.Contains(string(output), "concurrent map") && !strings.Contains(string(output), "DATA RACE") {
+		t.Fatalf("child process failed with %v, want a concurrent map fatal error:\n%s", err, output)
+	}
+}
+
+func TestAvoid71(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	for i := 0; i < 1000; i++ {
+		if finished := avoid71(); finished != wgGoroutines {
+			t.Fatalf("run %d: avoid71() = %d, want %d", i, finished, wgGoroutines)
+		}
+	}
+	waitForGoroutines(t, baseline)
+}
+
+// mistake71 is a data race, so it runs in a child process of the test binary, where -race can't fail this test.
+// The child fails if Wait returned early in any run, or if the race detector caught the misplaced Add.
+// Neither is guaranteed to happen, so the test is skipped when the child succeeds.
+func TestMistake71ReturnsEarly(t *testing.T) {
+	if os.Getenv("GOSTUDY_RUN_MISTAKE71") == "1" {
+		for i := 0; i < 1000; i++ {
+			if finished := mistake71(); finished != wgGoroutines {
+				fmt.Printf("Wait returned after %d of %d goroutines finished\n", finished, wgGoroutines)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestMistake71ReturnsEarly$")
+	cmd.Env = append(os.Environ(), "GOSTUDY_RUN_MISTAKE71=1")
+	output, err := cmd.CombinedOutput()
+
+	if err == nil {
+		t.Skip("Wait never returned early this time")
+	}
+	if ctx.Err() != nil {
+		t.Skip("mistake71 did not finish before the timeout")
+	}
+	if !strings.Contains(string(output), "Wait returned after") && !strings.Contains(string(output), "DATA RACE") {
+		t.Fatalf("child process failed with %v, want an early Wait or a data race:\n%s", err, output)
+	}
+	t.Logf("%s", strings.SplitN(string(output), "\n", 2)[0])
+}
+
+// BenchmarkWaitGroupAdd starts and waits for 100 goroutines, adding to the WaitGroup once for all of them,
+// or once before every go statement, against collecting them over a channel.
+// The Add calls are a few atomic additions, lost in the cost of starting the goroutines:
+// both WaitGroup placements take about 400ns per goroutine, and the channel, which costs a send
+// and a receive per goroutine, about 650ns. Adding before every go statement costs nothing noticeable.
+func BenchmarkWaitGroupAdd(b *testing.B) {
+	const goroutines = 100
+
+	b.Run("once", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			wg.Add(goroutines)
+			for g := 0; g < goroutines; g++ {
+				go func() {
+					defer wg.Done()
+					Consume(int64(g))
+				}()
+			}
+			wg.Wait()
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*goroutines), "ns/goroutine")
+	})
+
+	b.Run("per-goroutine", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					Consume(int64(g))
+				}()
+			}
+			wg.Wait()
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*goroutines), "ns/goroutine")
+	})
+
+	b.Run("channel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			done := make(chan struct{}, goroutines)
+			for g := 0; g < goroutines; g++ {
+				go func() {
+					Consume(int64(g))
+					done <- struct{}{}
+				}()
+			}
+			for g := 0; g < goroutines; g++ {
+				<-done
+			}
+		}
+		b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*goroutines), "ns/goroutine")
+	})
+}
+
+func TestTuneBufferSize(t *testing.T) {
+	const bursts = 5
+	const burstSize = 10
+
+	// The producer sends in bursts, and the consumer needs a while for every value.
+	// Without a buffer, the producer can't prepare the next burst while the consumer is busy.
+	produce := func(ch chan<- int) {
+		for i := 0; i < bursts; i++ {
+			time.Sleep(10 * time.Millisecond)
+			for j := 0; j < burstSize; j++ {
+				ch <- j
+			}
+		}
+	}
+	consume := func(ch <-chan int) {
+		for range ch {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	best, timings := TuneBufferSize(produce, consume, []int{0, burstSize})
+
+	if best != burstSize {
+		t.Fatalf("TuneBufferSize() = %d, want %d (timings: %v)", best, burstSize, timings)
+	}
+	if len(timings) != 2 {
+		t.Fatalf("got %d timings, want 2", len(timings))
+	}
+}
+
+func TestRunLoop(t *testing.T) {
+	t.Run("TicksUntilCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ticks := 0
+
+		err := RunLoop(ctx, time.Millisecond, func(context.Context) error {
+			ticks++
+			if ticks == 3 {
+				cancel()
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("RunLoop() = %v, want nil", err)
+		}
+		if ticks != 3 {
+			t.Fatalf("onTick called %d times, want 3", ticks)
+		}
+	})
+
+	t.Run("StopsOnError", func(t *testing.T) {
+		errTick := errors.New("tick failed")
+		ticks := 0
+
+		err := RunLoop(context.Background(), time.Millisecond, func(context.Context) error {
+			ticks++
+			if ticks == 2 {
+				return errTick
+			}
+			return nil
+		})
+
+		if !errors.Is(err, errTick) {
+			t.Fatalf("RunLoop() = %v, want %v", err, errTick)
+		}
+		if ticks != 2 {
+			t.Fatalf("onTick called %d times, want 2", ticks)
+		}
+	})
+
+	t.Run("AlreadyCancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := RunLoop(ctx, time.Hour, func(context.Context) error {
+			t.Fatal("onTick called after the context was cancelled")
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("RunLoop() = %v, want nil", err)
+		}
+	})
+}
+
+func TestMerge(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	if sum := mistake66(); sum != 21 {
+		t.Fatalf("mistake66() = %d, want 21", sum)
+	}
+	if sum := avoid66(); sum != 21 {
+		t.Fatalf("avoid66() = %d, want 21", sum)
+	}
+
+	sum := 0
+	for v := range mergeN(produce(1, 2), produce(3), produce(), produce(4, 5, 6)) {
+		sum += v
+	}
+	if sum != 21 {
+		t.Fatalf("mergeN() sum = %d, want 21", sum)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+// TestMergeKeepsLastValue checks that disabling a closed channel never drops the value sent right before
+// the close, with both unbuffered and buffered producers.
+func TestMergeKeepsLastValue(t *testing.T) {
+	const runs = 10000
+
+	oneValue := func(v, buffer int) <-chan int {
+		ch := make(chan int, buffer)
+		go func() {
+			ch <- v
+			close(ch)
+		}()
+		return ch
+	}
+
+	for i := 0; i < runs; i++ {
+		buffer := i % 2
+
+		sum := 0
+		for v := range merge(oneValue(1, buffer), oneValue(2, buffer)) {
+			sum += v
+		}
+		if sum != 3 {
+			t.Fatalf("run %d: merge() sum = %d, want 3", i, sum)
+		}
+
+		sum = 0
+		for v := range mergeN(oneValue(1, buffer), oneValue(2, buffer), oneValue(4, buffer)) {
+			sum += v
+		}
+		if sum != 7 {
+			t.Fatalf("run %d: mergeN() sum = %d, want 7", i, sum)
+		}
+	}
+}
+
+// TestSelectNonDeterminism runs the structure of mistake64 many times to show how often
+// the disconnection wins over messages that are still buffered, and checks that avoid64 never loses any.
+func TestSelectNonDeterminism(t *testing.T) {
+	const runs = 2000
+
+	early := 0
+	for i := 0; i < runs; i++ {
+		if mistake64() < 10 {
+			early++
+		}
+	}
+	// The rate depends on the scheduler and the number of CPUs, so it is only reported.
+	t.Logf("mistake64 returned before processing all 10 messages in %d of %d runs (%.1f%%)",
+		early, runs, 100*float64(early)/runs)
+
+	for i := 0; i < runs; i++ {
+		if processed := avoid64(); processed != 10 {
+			t.Fatalf("run %d: avoid64() processed %d messages, want 10", i, processed)
+		}
+	}
+}
+
+// BenchmarkMergeNProducers merges the output of a growing number of producers with mergeN.
+// Every value goes through the single output channel, so the throughput doesn't scale with the producers,
+// and it even drops, as reflect.Select has to set up every case again for each value.
+func BenchmarkMergeNProducers(b *testing.B) {
+	const valuesPerProducer = 1000
+
+	for _, producers := range []int{2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("producers=%d", producers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				channels := make([]<-chan int, producers)
+				for p := range channels {
+					ch := make(chan int)
+					go func() {
+						defer close(ch)
+						for v := 0; v < valuesPerProducer; v++ {
+							ch <- v
+						}
+					}()
+					channels[p] = ch
+				}
+
+				received := 0
+				for range mergeN(channels...) {
+					received++
+				}
+				if received != producers*valuesPerProducer {
+					b.Fatalf("received %d values, want %d", received, producers*valuesPerProducer)
+				}
+				Consume(int64(received))
+			}
+			b.ReportMetric(float64(b.N*producers*valuesPerProducer)/b.Elapsed().Seconds(), "values/s")
+		})
+	}
+}
+
+// produceSlowly sends values, doing some work before every value, so ch is empty most of the time.
+func produceSlowly(values int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for v := 0; v < values; v++ {
+			work := 0
+			for i := 0; i < 1000; i++ {
+				work += i * v
+			}
+			Consume(int64(work))
+			ch <- v
+		}
+	}()
+	return ch
+}
+
+func TestReceivePollingMatchesBlocking(t *testing.T) {
+	// Few values, as a polling receiver may starve the producer until the scheduler preempts it.
+	const want = 9 * 10 / 2
+	if sum := receiveBlocking(produceSlowly(10)); sum != want {
+		t.Fatalf("receiveBlocking() = %d, want %d", sum, want)
+	}
+	if sum, _ := receivePolling(produceSlowly(10)); sum != want {
+		t.Fatalf("receivePolling() = %d, want %d", sum, want)
+	}
+}
+
+// BenchmarkReceiveBlocking and BenchmarkReceivePolling receive 100 values from a slow producer.
+// Blocking takes about 155µs, the producer's own work. On a single CPU, polling takes about 2s,
+// with close to 3 million empty polls per value: once the producer blocks on its send and the poller runs,
+// the producer only gets the CPU back when the scheduler preempts the poller, every 10ms or so.
+// With more CPUs both run at the same time and polling isn't much slower, but it keeps a CPU at 100%.
+func BenchmarkReceiveBlocking(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Consume(receiveBlocking(produceSlowly(100)))
+	}
+}
+
+func BenchmarkReceivePolling(b *testing.B) {
+	empty := 0
+	for i := 0; i < b.N; i++ {
+		sum, n := receivePolling(produceSlowly(100))
+		Consume(sum)
+		empty += n
+	}
+	b.ReportMetric(float64(empty)/float64(b.N), "empty-polls/op")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config is the configuration a watcher (Rule 62) reloads and hands out to the rest of the program.
+// A Config is never modified once it is shared: a reload builds a new one and swaps the pointer.
+type Config struct {
+	Version  int
+	Interval time.Duration
+	Targets  []string
+}
+
+// ConfigStore hands out the current Config to any number of goroutines, while a watcher replaces it.
+// Get returns a Config that must not be modified, and Set must be given one that isn't modified afterwards,
+// so a reader keeps using a consistent Config even after the store moved on to the next one.
+//
+// Both implementations are correct. atomicConfig is the one to use: reads are cheaper,
+// and they don't slow down as more goroutines read (see BenchmarkConfigRead).
+type ConfigStore interface {
+	Get() *Config
+	Set(*Config)
+}
+
+var (
+	_ ConfigStore = (*atomicConfig)(nil)
+	_ ConfigStore = (*lockedConfig)(nil)
+)
+
+// atomicConfig hands out the current Config through an atomic.Pointer.
+// A reader only does an atomic load, so readers never wait, for each other or for a writer.
+type atomicConfig struct {
+	current atomic.Pointer[Config]
+}
+
+func (c *atomicConfig) Get() *Config {
+	return c.current.Load()
+}
+
+func (c *atomicConfig) Set(config *Config) {
+	c.current.Store(config)
+}
+
+// lockedConfig hands out the current Config under a RWMutex.
+// Readers don't block each other, but they all update the reader count of the same mutex,
+// so its cache line bounces between the CPUs, and a writer stalls the new readers.
+type lockedConfig struct {
+	mu      sync.RWMutex
+	current *Config
+}
+
+func (c *lockedConfig) Get() *Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+func (c *lockedConfig) Set(config *Config) {
+	c.mu.Lock()
+	c.current = config
+	c.mu.Unlock()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// "Do not communicate by sharing memory; instead, share memory by communicating."
+// Both counters below implement the same small service, one in each style.
+
+// actorCounter owns its count in a single goroutine.
+// Other goroutines never touch n, they send messages to the owner instead.
+type actorCounter struct {
+	incs  chan int64
+	reads chan chan int64
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newActorCounter() *actorCounter {
+	c := &actorCounter{
+		incs:  make(chan int64),
+		reads: make(chan chan int64),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *actorCounter) run() {
+	defer close(c.done)
+
+	var n int64
+	for {
+		select {
+		case delta := <-c.incs:
+			n += delta
+		case reply := <-c.reads:
+			reply <- n
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *actorCounter) Inc() {
+	c.incs <- 1
+}
+
+func (c *actorCounter) Value() int64 {
+	reply := make(chan int64)
+	c.reads <- reply
+	return <-reply
+}
+
+// Close stops the owner goroutine and waits for it to exit.
+// The counter must not be used afterwards.
+func (c *actorCounter) Close() {
+	close(c.stop)
+	<-c.done
+}
+
+// mutexCounter shares its count, and every access is serialized by the mutex.
+type mutexCounter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+func (c *mutexCounter) Inc() {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func (c *mutexCounter) Value() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// ShardedCounter spreads its count over one shard per P, so goroutines running in parallel
+// mostly increment different cache lines instead of all fighting over the same one.
+// Go doesn't expose which P a goroutine runs on, so Add picks a shard at random,
+// which is nearly as good at keeping concurrent writers apart.
+// Sum reads every shard, so it is slower than reading a single counter, and it is not a snapshot.
+type ShardedCounter struct {
+	shards []counterShard
+}
+
+// counterShard is padded to 64 bytes, so two shards never share a cache line (like FastResult).
+type counterShard struct {
+	n atomic.Int64
+	_ [56]byte
+}
+
+// NewShardedCounter creates a counter with GOMAXPROCS shards.
+func NewShardedCounter() *ShardedCounter {
+	return &ShardedCounter{shards: make([]counterShard, runtime.GOMAXPROCS(0))}
+}
+
+func (c *ShardedCounter) Add(delta int64) {
+	c.shards[rand.N(len(c.shards))].n.Add(delta)
+}
+
+func (c *ShardedCounter) Sum() int64 {
+	var sum int64
+	for i := range c.shards {
+		sum += c.shards[i].n.Load()
+	}
+	return sum
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+//===============================================
+// Rule 94 Not being aware of data alignment
+//===============================================
+
+// Every field is aligned to its own size, so the compiler inserts padding before an int64 that follows a bool,
+// and at the end of the struct, so the next element of an array is aligned too.
+// Go never reorders fields, so with the same fields, looseFields takes 40 bytes and packedFields 24.
+// Ordering the fields from the largest to the smallest alignment gives the minimal size.
+// It matters for types that are stored by the million, as every padding byte is loaded into the caches too.
+
+type looseFields struct {
+	active  bool  // 1 byte, then 7 bytes of padding
+	id      int64 // 8 bytes
+	deleted bool  // 1 byte, then 7 bytes of padding
+	score   int64 // 8 bytes
+	pinned  bool  // 1 byte, then 7 bytes of padding at the end
+}
+
+type packedFields struct {
+	id      int64
+	score   int64
+	active  bool
+	deleted bool
+	pinned  bool // 5 bytes of padding at the end
+}
+
+// printStructSizes writes the sizes of looseFields and packedFields to w.
+func printStructSizes(w io.Writer) {
+	fmt.Fprintf(w, "looseFields:  %d bytes\n", unsafe.Sizeof(looseFields{}))
+	fmt.Fprintf(w, "packedFields: %d bytes\n", unsafe.Sizeof(packedFields{}))
+}
+
+func sumLooseFields(values []looseFields) int64 {
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		if values[i].active {
+			sum += values[i].score
+		}
+	}
+	return sum
+}
+
+func sumPackedFields(values []packedFields) int64 {
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		if values[i].active {
+			sum += values[i].score
+		}
+	}
+	return sum
+}
@@ -0,0 +1,80 @@
+package main
+
+//===============================================
+// Rule 21 Inefficient slice initialization
+//===============================================
+
+// When append runs out of capacity, it allocates a bigger backing array and copies every element over.
+// The capacity roughly doubles each time (and grows by ~1.25x once the slice is large),
+// so appending n elements is amortized O(1) per element, but still costs about log(n) allocations
+// and copies, and leaves the smaller arrays behind as garbage.
+
+func appendGrowing(n int) []int {
+	var s []int
+	for i := 0; i < n; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+// If the final length is known (or can be bounded) up front, allocate the capacity once.
+// The same applies when converting one slice into another, which is the most common case.
+func appendPreallocated(n int) []int {
+	s := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+//===============================================
+// Rule 24 Not making slice copies correctly
+//===============================================
+
+// copy copies min(len(dst), len(src)) elements, so copying into a nil or empty slice copies nothing.
+// The destination has to be created with the length of the source first.
+func cloneViaCopy(s []int64) []int64 {
+	dst := make([]int64, len(s))
+	copy(dst, s)
+	return dst
+}
+
+// Appending to a nil slice gives the same result in one line.
+// append rounds the capacity up to the next size class, and returns nil for an empty s.
+func cloneViaAppend(s []int64) []int64 {
+	return append([]int64(nil), s...)
+}
+
+// CloneSlice returns a copy of s that shares no memory with it, using append, which was
+// as fast or slightly faster than make and copy in BenchmarkCloneViaAppend and BenchmarkCloneViaCopy
+// (append skips zeroing the new array before copying).
+// Appending to s[:0:0] instead of nil keeps an empty s non-nil, like slices.Clone.
+func CloneSlice[T any](s []T) []T {
+	return append(s[:0:0], s...)
+}
+
+//===============================================
+// Rule 27 Inefficient map initialization
+//===============================================
+
+// A map grows when it reaches its load factor: it allocates a bigger table and moves every entry over,
+// like append does for a slice, but rehashing the keys makes each growth more expensive than a copy.
+// Presizing matters when the number of entries is known up front and large, e.g. when indexing a slice.
+// For a handful of entries, or when the size is a guess far above the real count, it buys nothing
+// or wastes memory, as the table is allocated for the hint right away.
+
+func fillMapGrowing(keys []string) map[string]int {
+	m := make(map[string]int)
+	for i, key := range keys {
+		m[key] = i
+	}
+	return m
+}
+
+func fillMapPresized(keys []string) map[string]int {
+	m := make(map[string]int, len(keys))
+	for i, key := range keys {
+		m[key] = i
+	}
+	return m
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// BenchmarkAppendGrowing appends to a nil slice. allocs/op shows every reallocation of the backing array.
+func BenchmarkAppendGrowing(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(appendGrowing(10000))))
+	}
+}
+
+// BenchmarkAppendPreallocated appends to a slice created with enough capacity. It allocates exactly once.
+func BenchmarkAppendPreallocated(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(appendPreallocated(10000))))
+	}
+}
+
+func TestCloneSlice(t *testing.T) {
+	original := []int64{1, 2, 3}
+
+	for _, testcase := range []struct {
+		name  string
+		clone func([]int64) []int64
+	}{
+		{"CloneViaCopy", cloneViaCopy},
+		{"CloneViaAppend", cloneViaAppend},
+		{"CloneSlice", CloneSlice[int64]},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			clone := testcase.clone(original)
+			if !slices.Equal(clone, original) {
+				t.Fatalf("clone = %v, want %v", clone, original)
+			}
+
+			clone[0] = 100
+			clone = append(clone, 4)
+			if !slices.Equal(original, []int64{1, 2, 3}) {
+				t.Fatalf("original = %v after mutating the clone, want [1 2 3]", original)
+			}
+		})
+	}
+
+	if clone := CloneSlice([]int64{}); clone == nil {
+		t.Fatal("CloneSlice(empty) = nil, want an empty non-nil slice")
+	}
+	if clone := CloneSlice([]int64(nil)); clone != nil {
+		t.Fatalf("CloneSlice(nil) = %v, want nil", clone)
+	}
+}
+
+func BenchmarkCloneViaCopy(b *testing.B) {
+	s := appendPreallocatedInt64(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(cloneViaCopy(s)[len(s)-1])
+	}
+}
+
+func BenchmarkCloneViaAppend(b *testing.B) {
+	s := appendPreallocatedInt64(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(cloneViaAppend(s)[len(s)-1])
+	}
+}
+
+func appendPreallocatedInt64(n int) []int64 {
+	s := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		s = append(s, int64(i))
+	}
+	return s
+}
+
+// BenchmarkFillMapGrowing and BenchmarkFillMapPresized insert the same keys into a map created
+// without and with a size hint. allocs/op shows every growth of the table.
+func BenchmarkFillMapGrowing(b *testing.B) {
+	keys := newKeys(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(fillMapGrowing(keys))))
+	}
+}
+
+func BenchmarkFillMapPresized(b *testing.B) {
+	keys := newKeys(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(fillMapPresized(keys))))
+	}
+}
+
+func newKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	return keys
+}
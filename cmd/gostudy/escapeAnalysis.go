@@ -0,0 +1,32 @@
+package main
+
+// The compiler decides for every variable whether it can live on the goroutine's stack,
+// or has to escape to the heap because it may outlive the function that created it.
+// Stack allocation is free: the memory is reclaimed when the function returns, and the GC never sees it.
+//
+// Run `go build -gcflags="-m" ./cmd/gostudy` to print the decisions. For the functions below, it reports
+// "moved to heap: p" for heapAlloc, and nothing for stackAlloc.
+
+type point struct {
+	x, y int64
+}
+
+// stackAlloc returns a copy of p, so p itself never outlives the call.
+//
+// Inlining would let the compiler see through the call and keep heapAlloc's result on the caller's stack,
+// so both functions are marked noinline to keep the example honest.
+//
+//go:noinline
+func stackAlloc() point {
+	p := point{x: 1, y: 2}
+	return p
+}
+
+// heapAlloc returns a pointer to its local variable. The pointer outlives the call,
+// so p escapes and is allocated on the heap.
+//
+//go:noinline
+func heapAlloc() *point {
+	p := point{x: 1, y: 2}
+	return &p
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestStackAndHeapAllocReturnSameValue(t *testing.T) {
+	if p := heapAlloc(); *p != stackAlloc() {
+		t.Fatalf("heapAlloc() = %+v, stackAlloc() = %+v; want equal", *p, stackAlloc())
+	}
+}
+
+// BenchmarkStackAlloc reports 0 allocs/op.
+func BenchmarkStackAlloc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := stackAlloc()
+		Consume(p.x)
+	}
+}
+
+// BenchmarkHeapAlloc reports 1 allocs/op, the escaped point.
+func BenchmarkHeapAlloc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := heapAlloc()
+		Consume(p.x)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// fmt.Sprintf parses its format string at runtime and takes its arguments as interface values,
+// so formatting an int boxes it (see SumIface) and goes through reflection-based dispatch.
+// strconv.Itoa does only the conversion. In hot loops, prefer strconv,
+// or strconv.AppendInt into a reused buffer, which doesn't allocate at all.
+
+func formatSprintf(n int) string {
+	return fmt.Sprintf("%d", n)
+}
+
+func formatItoa(n int) string {
+	return strconv.Itoa(n)
+}
+
+// appendDecimal appends the decimal digits of n to buf, like strconv.AppendInt(buf, int64(n), 10).
+// It writes the digits backwards into a fixed array on the stack, and copies them into buf once.
+func appendDecimal(buf []byte, n int) []byte {
+	var digits [20]byte // len("-9223372036854775808") == 20
+	i := len(digits)
+
+	u := uint64(n)
+	if n < 0 {
+		u = -u
+	}
+	for {
+		i--
+		digits[i] = byte('0' + u%10)
+		u /= 10
+		if u == 0 {
+			break
+		}
+	}
+	if n < 0 {
+		i--
+		digits[i] = '-'
+	}
+	return append(buf, digits[i:]...)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatVariantsAgree(t *testing.T) {
+	for _, n := range []int{0, 7, -7, 42, 1000000, math.MaxInt64, math.MinInt64} {
+		want := formatSprintf(n)
+		if got := formatItoa(n); got != want {
+			t.Fatalf("formatItoa(%d) = %q, want %q", n, got, want)
+		}
+		if got := string(appendDecimal(nil, n)); got != want {
+			t.Fatalf("appendDecimal(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// BenchmarkFormatSprintf, BenchmarkFormatItoa and BenchmarkAppendDecimal format the same numbers.
+// Sprintf allocates for the boxed argument and for the string, Itoa only for the string
+// (small numbers are even cached), and appendDecimal reuses its buffer.
+func BenchmarkFormatSprintf(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(formatSprintf(i + 1000))))
+	}
+}
+
+func BenchmarkFormatItoa(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Consume(int64(len(formatItoa(i + 1000))))
+	}
+}
+
+func BenchmarkAppendDecimal(b *testing.B) {
+	buf := make([]byte, 0, 20)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = appendDecimal(buf[:0], i+1000)
+		Consume(int64(len(buf)))
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitForGoroutines fails the test if the number of goroutines doesn't drop back to baseline.
+// Goroutines exit asynchronously, so it polls for a while before giving up.
+func waitForGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= baseline {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutine leak: %d goroutines running, want at most %d", n, baseline)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
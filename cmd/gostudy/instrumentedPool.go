@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// InstrumentedPool is a sync.Pool of T that counts how often it actually reuses a value.
+// A pool only pays off if most Gets are served by a value that was Put back,
+// which depends on the GC (every cycle drops the values idle since the previous one) and on the
+// balance of Gets and Puts, so it is worth measuring rather than assuming.
+//
+// Like with sync.Pool, T should be a pointer type: a non-pointer value is boxed into an interface
+// on every Put (see SumIface), which allocates as much as the pool was supposed to save.
+type InstrumentedPool[T any] struct {
+	pool sync.Pool
+	gets atomic.Int64
+	news atomic.Int64
+	puts atomic.Int64
+}
+
+// InstrumentedPoolStats is a snapshot of the counters of an InstrumentedPool.
+// Gets-News is the number of Gets served by a pooled value.
+type InstrumentedPoolStats struct {
+	Gets int64 // calls to Get
+	News int64 // calls to the factory, i.e. Gets that found the pool empty
+	Puts int64 // calls to Put
+}
+
+// NewInstrumentedPool returns an empty pool, which creates new values with newFn.
+func NewInstrumentedPool[T any](newFn func() T) *InstrumentedPool[T] {
+	p := &InstrumentedPool[T]{}
+	p.pool.New = func() any {
+		p.news.Add(1)
+		return newFn()
+	}
+	return p
+}
+
+// Get returns a pooled value if there is one, and a new one otherwise.
+func (p *InstrumentedPool[T]) Get() T {
+	p.gets.Add(1)
+	return p.pool.Get().(T)
+}
+
+// Put hands v back to the pool. v must not be used afterwards.
+func (p *InstrumentedPool[T]) Put(v T) {
+	p.puts.Add(1)
+	p.pool.Put(v)
+}
+
+// Stats returns the current counters. They are read one by one, so while the pool is in use,
+// they may be off by the calls that happened in between.
+func (p *InstrumentedPool[T]) Stats() InstrumentedPoolStats {
+	return InstrumentedPoolStats{
+		Gets: p.gets.Load(),
+		News: p.news.Load(),
+		Puts: p.puts.Load(),
+	}
+}
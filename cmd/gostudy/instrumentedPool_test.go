@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestInstrumentedPoolMisses(t *testing.T) {
+	pool := NewInstrumentedPool(func() *[]Foo {
+		foo := make([]Foo, 16)
+		return &foo
+	})
+
+	// Nothing was Put, so every Get calls the factory.
+	for range 3 {
+		pool.Get()
+	}
+
+	if got, want := pool.Stats(), (InstrumentedPoolStats{Gets: 3, News: 3}); got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestInstrumentedPoolHits(t *testing.T) {
+	pool := NewInstrumentedPool(func() *[]Foo {
+		foo := make([]Foo, 16)
+		return &foo
+	})
+
+	const rounds = 100
+	for range rounds {
+		foo := pool.Get()
+		pool.Put(foo)
+	}
+
+	stats := pool.Stats()
+	if stats.Gets != rounds || stats.Puts != rounds {
+		t.Fatalf("Stats() = %+v, want %d Gets and Puts", stats, rounds)
+	}
+	// A value Put back may still be dropped, by a GC or on purpose by the race detector,
+	// so only the first Get is sure to miss.
+	if stats.News < 1 || stats.News > rounds/2 {
+		t.Fatalf("Stats().News = %d, want between 1 and %d", stats.News, rounds/2)
+	}
+}
+
+func TestInstrumentedPoolConcurrent(t *testing.T) {
+	pool := NewInstrumentedPool(func() *int { return new(int) })
+
+	const goroutines, rounds = 8, 1000
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for range goroutines {
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				v := pool.Get()
+				*v++
+				pool.Put(v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := pool.Stats()
+	if stats.Gets != goroutines*rounds || stats.Puts != goroutines*rounds {
+		t.Fatalf("Stats() = %+v, want %d Gets and Puts", stats, goroutines*rounds)
+	}
+	if stats.News < 1 || stats.News > stats.Gets {
+		t.Fatalf("Stats().News = %d, want between 1 and %d", stats.News, stats.Gets)
+	}
+}
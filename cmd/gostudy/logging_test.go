@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// recordingHandler keeps the messages of every record it handles.
+type recordingHandler struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, record.Message)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+func (h *recordingHandler) count(message string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for _, m := range h.messages {
+		if m == message {
+			n++
+		}
+	}
+	return n
+}
+
+func recordDebugEvents(t *testing.T) *recordingHandler {
+	t.Helper()
+	handler := &recordingHandler{}
+	SetDebugLogger(slog.New(handler))
+	t.Cleanup(func() { SetDebugLogger(nil) })
+	return handler
+}
+
+func TestWatcherLogsStartAndStop(t *testing.T) {
+	handler := recordDebugEvents(t)
+
+	w := newWatcher()
+	w.close()
+
+	handler.mu.Lock()
+	messages := slices.Clone(handler.messages)
+	handler.mu.Unlock()
+	if want := []string{"watcher started", "watcher stopped"}; !slices.Equal(messages, want) {
+		t.Fatalf("logged %q, want %q", messages, want)
+	}
+}
+
+func TestPoolAndMergeLogEvents(t *testing.T) {
+	handler := recordDebugEvents(t)
+
+	pool := NewWorkerPool(3, func(v int) int { return v })
+	pool.Close()
+	for range mergeN(produce(1), produce(2)) {
+	}
+
+	if n := handler.count("pool worker started"); n != 3 {
+		t.Fatalf("logged %d worker starts, want 3", n)
+	}
+	if n := handler.count("pool worker stopped"); n != 3 {
+		t.Fatalf("logged %d worker stops, want 3", n)
+	}
+	if n := handler.count("merge disabled a closed channel"); n != 2 {
+		t.Fatalf("logged %d disabled channels, want 2", n)
+	}
+}
+
+// BenchmarkDisabledDebugLog measures the cost of events when the debug logger is disabled.
+func BenchmarkDisabledDebugLog(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger().Debug("pool worker started")
+		if debugEnabled() {
+			logger().Debug("merge disabled a closed channel", slog.Int("channel", i))
+		}
+	}
+}
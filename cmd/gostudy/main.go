@@ -1,13 +1,79 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
 	// This controls the maxprocs environment variable in container runtimes.
 	// see https://martin.baillie.id/wrote/gotchas-in-the-go-network-packages-defaults/#bonus-gomaxprocs-containers-and-the-cfs
 )
 
 func main() {
+	// The flags live in a local FlagSet rather than in package-level variables.
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	pprofAddr := flags.String("pprof-addr", "", "serve net/http/pprof on this address while the benchmarks run, e.g. localhost:6060")
+	selfTest := flags.Bool("self-test", false, "run the correct version of every concurrency example and exit, best with go run -race")
+	_ = flags.Parse(os.Args[1:]) // ExitOnError exits on a bad flag instead of returning an error.
+
+	if *selfTest {
+		if err := RunAllSafeExamples(); err != nil {
+			fmt.Println("Self-test failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Self-test passed")
+		return
+	}
+
+	// ctx is cancelled on an interrupt, or once the benchmarks are done.
+	// Everything started in the background stops when ctx is cancelled, and main waits for it before returning.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if !runtimeFollowsCgroupQuota() {
+		if _, err := SetMaxProcsFromCgroup(); err != nil {
+			fmt.Println("Failed to read the CPU quota, keeping the default GOMAXPROCS:", err)
+		}
+	}
+
+	var pprofDone <-chan error
+	if *pprofAddr != "" {
+		addr, done, err := servePprof(ctx, *pprofAddr)
+		if err != nil {
+			fmt.Println("Failed to start the pprof server:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Serving pprof on http://%s/debug/pprof/\n", addr)
+		pprofDone = done
+	}
+	waitBackground := func() {
+		if pprofDone != nil {
+			if err := <-pprofDone; err != nil {
+				fmt.Println("Failed to shut down the pprof server:", err)
+			}
+		}
+	}
+
+	// The benchmarks don't take a context, so an interrupt exits the process
+	// once everything in the background has stopped.
+	finished := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		select {
+		case <-finished:
+		default:
+			waitBackground()
+			os.Exit(130)
+		}
+	}()
+
 	fmt.Println("Running simple performance benchmark...")
-	// SimpleBenchmark()
-	CountBenchmark()
+	// SimpleBenchmark(os.Stdout)
+	CountBenchmark(os.Stdout)
+	printStructSizes(os.Stdout)
+
+	close(finished)
+	stop()
+	waitBackground()
 }
@@ -0,0 +1,6 @@
+//go:build !go1.25
+
+package main
+
+// toolchainFollowsCgroupQuota reports whether the runtime can set GOMAXPROCS from the cgroup CPU quota on its own.
+const toolchainFollowsCgroupQuota = false
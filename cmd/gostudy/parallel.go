@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// SyncMultiError collects errors from many goroutines.
+// Unlike MultiError (Rule 45), which flattens errors into strings,
+// it keeps the original errors so callers can still use errors.Is and errors.As on them.
+type SyncMultiError struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Add records err. nil errors are ignored.
+func (m *SyncMultiError) Add(err error) {
+	if err == nil {
+		return
+	}
+	m.mu.Lock()
+	m.errs = append(m.errs, err)
+	m.mu.Unlock()
+}
+
+// ErrorOrNil returns the recorded errors joined with errors.Join, or nil if there are none.
+// Returning a nil error interface, rather than a nil *SyncMultiError, avoids the trap of Rule 45.
+func (m *SyncMultiError) ErrorOrNil() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return errors.Join(m.errs...)
+}
+
+// ParallelMapAll runs fn on every input in its own goroutine and waits for all of them,
+// even when some fail. outputs[i] is the output of inputs[i], and is left as the zero value if fn failed.
+// All failures are returned together, joined into one error.
+func ParallelMapAll[T, R any](inputs []T, fn func(T) (R, error)) ([]R, error) {
+	outputs := make([]R, len(inputs))
+	var errs SyncMultiError
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(inputs))
+	for i := 0; i < len(inputs); i++ {
+		go func() {
+			defer wg.Done()
+			output, err := fn(inputs[i])
+			if err != nil {
+				errs.Add(err)
+				return
+			}
+			outputs[i] = output
+		}()
+	}
+	wg.Wait()
+
+	return outputs, errs.ErrorOrNil()
+}
+
+// Gather runs fns concurrently, at most limit at a time, and gathers their results.
+// results[i] is the result of fns[i], or nil if it failed, so callers type-assert the results they expect.
+// Unlike ParallelMapAll, the errors are joined in the order of fns rather than in the order they happened.
+func Gather(fns []func() (any, error), limit int) ([]any, error) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	results := make([]any, len(fns))
+	errs := make([]error, len(fns))
+	// Every running function holds one slot of the semaphore.
+	sem := make(chan struct{}, limit)
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(fns))
+	for i, fn := range fns {
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result, err := fn()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// ctxCheckInterval is how many elements a worker sums between two checks of its context.
+// Checking on every element would cost more than the addition itself.
+const ctxCheckInterval = 4096
+
+// AggregateInputs sums inputs by fanning out contiguous ranges to workers goroutines,
+// and fanning their partial results back in over a channel.
+// If ctx is cancelled, the workers stop within ctxCheckInterval elements and ctx.Err() is returned.
+func AggregateInputs(ctx context.Context, inputs []Input, workers int) (Result, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// The channel is buffered for every worker, so a worker never blocks on sending
+	// even when the collector has already returned because of a cancellation.
+	partials := make(chan Result, workers)
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			start, end := w*len(inputs)/workers, (w+1)*len(inputs)/workers
+			partial := Result{}
+			for i := start; i < end; i++ {
+				if (i-start)%ctxCheckInterval == 0 && ctx.Err() != nil {
+					errs <- ctx.Err()
+					return
+				}
+				partial.sumA += inputs[i].a
+				partial.sumB += inputs[i].b
+			}
+			partials <- partial
+		}()
+	}
+
+	result := Result{}
+	for w := 0; w < workers; w++ {
+		select {
+		case partial := <-partials:
+			result.sumA += partial.sumA
+			result.sumB += partial.sumB
+		case err := <-errs:
+			return Result{}, err
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+	}
+	return result, nil
+}
+
+// GroupByConcurrent groups items by keyFn, splitting the items into contiguous ranges across workers goroutines.
+// Every worker fills its own map and stores it at its own index of locals, so neither needs
+// synchronisation (see Rules 69 and 70). The local maps are merged once, after every worker is done.
+// Within a group, the items keep their order in items.
+func GroupByConcurrent[K comparable, V any](items []V, workers int, keyFn func(V) K) map[K][]V {
+	if workers < 1 {
+		workers = 1
+	}
+
+	locals := make([]map[K][]V, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make(map[K][]V)
+			for _, item := range items[w*len(items)/workers : (w+1)*len(items)/workers] {
+				key := keyFn(item)
+				local[key] = append(local[key], item)
+			}
+			locals[w] = local
+		}()
+	}
+	wg.Wait()
+
+	// The merge runs in worker order, so every group keeps the order of items.
+	groups := make(map[K][]V)
+	for _, local := range locals {
+		for key, values := range local {
+			groups[key] = append(groups[key], values...)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSyncMultiErrorEmpty(t *testing.T) {
+	var errs SyncMultiError
+	errs.Add(nil)
+
+	if err := errs.ErrorOrNil(); err != nil {
+		t.Fatalf("ErrorOrNil() = %v, want nil", err)
+	}
+}
+
+func TestParallelMapAll(t *testing.T) {
+	errNegative := errors.New("negative input")
+	errTooLarge := errors.New("input too large")
+
+	inputs := []int{1, -2, 3, 100, -5, 6}
+	outputs, err := ParallelMapAll(inputs, func(v int) (int, error) {
+		switch {
+		case v < 0:
+			return 0, fmt.Errorf("input %d: %w", v, errNegative)
+		case v > 10:
+			return 0, fmt.Errorf("input %d: %w", v, errTooLarge)
+		}
+		return v * 10, nil
+	})
+
+	if !errors.Is(err, errNegative) {
+		t.Fatalf("errors.Is(%v, errNegative) = false, want true", err)
+	}
+	if !errors.Is(err, errTooLarge) {
+		t.Fatalf("errors.Is(%v, errTooLarge) = false, want true", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 3 {
+		t.Fatalf("joined %d errors, want 3", n)
+	}
+
+	wants := []int{10, 0, 30, 0, 0, 60}
+	for i := range wants {
+		if outputs[i] != wants[i] {
+			t.Fatalf("outputs = %v, want %v", outputs, wants)
+		}
+	}
+}
+
+func TestAggregateInputs(t *testing.T) {
+	inputs := newInputs(100003)
+	want := sumInputs(inputs)
+
+	for _, workers := range []int{1, 4, 7} {
+		baseline := runtime.NumGoroutine()
+
+		got, err := AggregateInputs(context.Background(), inputs, workers)
+
+		if err != nil || got != want {
+			t.Fatalf("AggregateInputs(%d workers) = %+v, %v; want %+v, nil", workers, got, err, want)
+		}
+		waitForGoroutines(t, baseline)
+	}
+}
+
+func TestAggregateInputsCancelled(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	inputs := newInputs(1 << 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := AggregateInputs(ctx, inputs, 4)
+
+	if !errors.Is(err, context.Canceled) || got != (Result{}) {
+		t.Fatalf("AggregateInputs() = %+v, %v; want zero Result, %v", got, err, context.Canceled)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestAggregateInputsCancelledWhileRunning(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	inputs := newInputs(1 << 22)
+	want := sumInputs(inputs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+
+	// Depending on the scheduling, the sum may finish before the cancellation is observed.
+	got, err := AggregateInputs(ctx, inputs, 4)
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("AggregateInputs() error = %v, want nil or %v", err, context.Canceled)
+	}
+	if err == nil && got != want {
+		t.Fatalf("AggregateInputs() = %+v, want %+v", got, want)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestGroupByConcurrent(t *testing.T) {
+	items := make([]int, 100_000)
+	for i := range items {
+		items[i] = i
+	}
+	keyFn := func(v int) int { return v % 37 }
+
+	want := make(map[int][]int)
+	for _, item := range items {
+		want[keyFn(item)] = append(want[keyFn(item)], item)
+	}
+
+	for _, workers := range []int{0, 1, 3, 8, 200_000} {
+		got := GroupByConcurrent(items, workers, keyFn)
+
+		if len(got) != len(want) {
+			t.Fatalf("GroupByConcurrent(%d workers) has %d groups, want %d", workers, len(got), len(want))
+		}
+		for key, values := range got {
+			slices.Sort(values)
+			if !slices.Equal(values, want[key]) {
+				t.Fatalf("GroupByConcurrent(%d workers) group %d = %v, want %v", workers, key, values, want[key])
+			}
+		}
+	}
+}
+
+func TestGather(t *testing.T) {
+	errFirst := errors.New("first failed")
+	errLast := errors.New("last failed")
+
+	results, err := Gather([]func() (any, error){
+		func() (any, error) { return 42, nil },
+		func() (any, error) { return "answer", nil },
+		func() (any, error) { return 4.2, nil },
+	}, 2)
+
+	if err != nil {
+		t.Fatalf("Gather() error = %v, want nil", err)
+	}
+	if n, ok := results[0].(int); !ok || n != 42 {
+		t.Fatalf("results[0] = %v, want 42", results[0])
+	}
+	if s, ok := results[1].(string); !ok || s != "answer" {
+		t.Fatalf("results[1] = %v, want %q", results[1], "answer")
+	}
+	if f, ok := results[2].(float64); !ok || f != 4.2 {
+		t.Fatalf("results[2] = %v, want 4.2", results[2])
+	}
+
+	results, err = Gather([]func() (any, error){
+		func() (any, error) { return nil, errFirst },
+		func() (any, error) { return "ok", nil },
+		func() (any, error) { return nil, errLast },
+	}, 3)
+
+	if !errors.Is(err, errFirst) || !errors.Is(err, errLast) {
+		t.Fatalf("Gather() error = %v, want both %v and %v", err, errFirst, errLast)
+	}
+	if want := errFirst.Error() + "\n" + errLast.Error(); err.Error() != want {
+		t.Fatalf("Gather() error = %q, want %q", err, want)
+	}
+	if results[0] != nil || results[1] != "ok" || results[2] != nil {
+		t.Fatalf("Gather() = %v, want [<nil> ok <nil>]", results)
+	}
+}
+
+func TestGatherLimit(t *testing.T) {
+	const limit = 3
+	var running, peak atomic.Int64
+
+	fns := make([]func() (any, error), 20)
+	for i := range fns {
+		fns[i] = func() (any, error) {
+			now := running.Add(1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return i, nil
+		}
+	}
+
+	results, err := Gather(fns, limit)
+
+	if err != nil {
+		t.Fatalf("Gather() error = %v, want nil", err)
+	}
+	for i, result := range results {
+		if result != i {
+			t.Fatalf("results[%d] = %v, want %d", i, result, i)
+		}
+	}
+	if peak.Load() > limit {
+		t.Fatalf("%d functions ran at once, want at most %d", peak.Load(), limit)
+	}
+}
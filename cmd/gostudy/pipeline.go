@@ -0,0 +1,868 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Generate emits start, start+step, start+2*step, ... until ctx is cancelled.
+// It never ends on its own, so cancelling ctx is the only way to stop its goroutine.
+func Generate(ctx context.Context, start, step int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for v := start; ; v += step {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// GenerateRate is Generate emitting at most perSecond values per second.
+// The goroutine sleeps on a ticker between values instead of spinning, and a slow consumer doesn't
+// cause a burst to catch up, because the ticker drops the ticks nobody was ready for.
+// GenerateRate panics if perSecond isn't positive (or is NaN). A rate above a value per nanosecond,
+// up to +Inf, is capped at one value per nanosecond, the shortest interval of a ticker, and a rate so low
+// that its interval doesn't fit in a time.Duration gets the longest one instead of overflowing.
+func GenerateRate(ctx context.Context, start, step int, perSecond float64) <-chan int {
+	if !(perSecond > 0) {
+		panic(fmt.Sprintf("GenerateRate: perSecond must be positive, got %v", perSecond))
+	}
+	interval := time.Duration(math.MaxInt64)
+	if nanos := float64(time.Second) / perSecond; nanos < float64(math.MaxInt64) {
+		interval = max(time.Duration(nanos), time.Nanosecond)
+	}
+
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for v := start; ; v += step {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Take forwards the first n values of in, and then closes its output.
+// It stops reading in after n values, so the producer of in must be stopped separately.
+func Take[T any](in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			v, ok := <-in
+			if !ok {
+				return
+			}
+			out <- v
+		}
+	}()
+	return out
+}
+
+// Map applies fn to every value of in, and closes its output when in closes.
+func Map[T, R any](in <-chan T, fn func(T) R) <-chan R {
+	out := make(chan R)
+	go func() {
+		defer close(out)
+		for v := range in {
+			out <- fn(v)
+		}
+	}()
+	return out
+}
+
+// Filter forwards the values of in for which pred returns true, and closes its output when in closes.
+func Filter[T any](in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range in {
+			if pred(v) {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+// StageErr is Map for an fn that can fail: successful results go to the first channel,
+// and errors to the second. Both are closed when in closes.
+//
+// Both channels are unbuffered and fed by the same goroutine, so the caller must drain them together,
+// e.g. in a single select loop, until both are closed. Reading only the results blocks the stage
+// on the first error, and with it the producer of in, and leaks all of their goroutines.
+func StageErr[T, R any](in <-chan T, fn func(T) (R, error)) (<-chan R, <-chan error) {
+	out := make(chan R)
+	errs := make(chan error)
+	go func() {
+		defer close(out)
+		defer close(errs)
+		for v := range in {
+			r, err := fn(v)
+			if err != nil {
+				errs <- err
+				continue
+			}
+			out <- r
+		}
+	}()
+	return out, errs
+}
+
+// OrderedMapChan is Map running fn on up to workers values at a time, which still emits the results
+// in the order of in, and closes its output when in closes.
+//
+// Every value gets a sequence number, and results that finish early wait in a reorder buffer
+// until all the results before them have been emitted. A value only starts once it holds one of
+// workers tokens, which are given back when its result is emitted, so a slow value can hold up
+// at most workers-1 finished results, and the buffer stays bounded.
+func OrderedMapChan[T, R any](in <-chan T, workers int, fn func(T) R) <-chan R {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type job struct {
+		seq   int
+		value T
+	}
+	type result struct {
+		seq   int
+		value R
+	}
+
+	tokens := make(chan struct{}, workers)
+	jobs := make(chan job)
+	results := make(chan result)
+	out := make(chan R)
+
+	go func() {
+		defer close(jobs)
+		seq := 0
+		for v := range in {
+			tokens <- struct{}{}
+			jobs <- job{seq: seq, value: v}
+			seq++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- result{seq: j.seq, value: fn(j.value)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer close(out)
+		pending := make(map[int]R, workers)
+		next := 0
+		for r := range results {
+			pending[r.seq] = r.value
+			for {
+				v, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				out <- v
+				<-tokens
+				next++
+			}
+		}
+	}()
+	return out
+}
+
+// Collect receives values from in until it has limit values, in is closed, or ctx is cancelled,
+// whichever comes first. A limit of zero or less means no limit.
+// Collect doesn't read from in after returning, so an infinite producer must be stopped by its own context.
+func Collect[T any](ctx context.Context, in <-chan T, limit int) []T {
+	var values []T
+	for limit <= 0 || len(values) < limit {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return values
+			}
+			values = append(values, v)
+		case <-ctx.Done():
+			return values
+		}
+	}
+	return values
+}
+
+// FromSlice returns a channel that emits the elements of s in order, and is then closed.
+// The channel is buffered with all of s, so no goroutine is needed to feed it.
+func FromSlice[T any](s []T) <-chan T {
+	out := make(chan T, len(s))
+	for _, v := range s {
+		out <- v
+	}
+	close(out)
+	return out
+}
+
+// ToSlice receives from ch until it is closed. It is Collect without a limit or a context,
+// so ch must be finite.
+func ToSlice[T any](ch <-chan T) []T {
+	var s []T
+	for v := range ch {
+		s = append(s, v)
+	}
+	return s
+}
+
+// ReduceByKey folds the values of in per key as they arrive, starting every key from initial(),
+// and returns the aggregates once in is closed. Like ToSlice, in must be finite.
+// Only the aggregates are kept, so memory grows with the number of keys, not with the number of values.
+// It runs on the calling goroutine, so reduce needs no synchronization.
+func ReduceByKey[K comparable, V, R any](in <-chan struct {
+	Key K
+	Val V
+}, reduce func(R, V) R, initial func() R) map[K]R {
+	aggregates := make(map[K]R)
+	for pair := range in {
+		acc, ok := aggregates[pair.Key]
+		if !ok {
+			acc = initial()
+		}
+		aggregates[pair.Key] = reduce(acc, pair.Val)
+	}
+	return aggregates
+}
+
+// Batch groups values from in into slices of up to size elements.
+// A batch is emitted as soon as it is full, or when maxWait has passed since its first element.
+// A maxWait of zero or less disables the time-based flush.
+//
+// When in closes, the timer is stopped and the pending partial batch is emitted exactly once
+// before the output is closed. An empty batch is never emitted.
+func Batch[T any](in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	if size < 1 {
+		size = 1
+	}
+
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+
+		timer := time.NewTimer(maxWait)
+		timer.Stop()
+		defer timer.Stop()
+
+		var batch []T
+		// timeout stays nil while the batch is empty. Receiving from a nil channel blocks forever,
+		// so the case is disabled until the first element of the next batch arrives.
+		var timeout <-chan time.Time
+
+		flush := func() {
+			out <- batch
+			batch = nil
+			timer.Stop()
+			timeout = nil
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						flush()
+					}
+					return
+				}
+				if len(batch) == 0 && maxWait > 0 {
+					timer.Reset(maxWait)
+					timeout = timer.C
+				}
+				batch = append(batch, v)
+				if len(batch) == size {
+					flush()
+				}
+			case <-timeout:
+				flush()
+			}
+		}
+	}()
+	return out
+}
+
+// BatchProcess groups the values of in like Batch, and hands every batch to flush on the calling goroutine,
+// so there is nothing to stop when it returns. A failing flush doesn't stop the processing:
+// the values of a batch are gone once flushed, so later batches are still worth trying.
+// flush may keep its slice, every batch is a new one.
+//
+// It returns when in is closed, or when ctx is cancelled. In both cases the pending partial batch is flushed first,
+// as its values were already received. The returned error joins the errors of every failed flush,
+// in order, followed by ctx.Err() if processing was cancelled, so it is nil only if everything was flushed.
+func BatchProcess[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration, flush func([]T) error) error {
+	if size < 1 {
+		size = 1
+	}
+
+	timer := time.NewTimer(maxWait)
+	timer.Stop()
+	defer timer.Stop()
+
+	var batch []T
+	var timeout <-chan time.Time
+	var errs []error
+
+	flushBatch := func() {
+		if len(batch) > 0 {
+			if err := flush(batch); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		batch = nil
+		timer.Stop()
+		timeout = nil
+	}
+
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				flushBatch()
+				return errors.Join(errs...)
+			}
+			if len(batch) == 0 && maxWait > 0 {
+				timer.Reset(maxWait)
+				timeout = timer.C
+			}
+			batch = append(batch, v)
+			if len(batch) == size {
+				flushBatch()
+			}
+		case <-timeout:
+			flushBatch()
+		case <-ctx.Done():
+			flushBatch()
+			errs = append(errs, ctx.Err())
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// Flatten emits every element of every slice received from in, in order, and closes its output when in closes.
+// It is the inverse of Batch. Like Map, it stops only when in is closed.
+func Flatten[T any](in <-chan []T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for batch := range in {
+			for _, v := range batch {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+// receivedValue converts a value received by reflect.Select back to T.
+// The comma-ok form turns a nil interface value, e.g. a nil error sent on a chan error,
+// into the zero T instead of panicking.
+func receivedValue[T any](v reflect.Value) T {
+	t, _ := v.Interface().(T)
+	return t
+}
+
+// MergeStoppable merges channels like mergeN (Rule 66), and also returns a stop function
+// for a consumer that wants to quit early. stop makes the merge goroutine return, even if it is blocked
+// sending a value nobody will receive, closes the output, and waits for both before returning.
+// It is safe to call stop more than once, and after every input has been closed.
+// The merge stops reading the inputs, so their producers must be stopped separately.
+func MergeStoppable[T any](channels ...<-chan T) (<-chan T, func()) {
+	out := make(chan T)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	// The stop channel is case 0, the inputs follow.
+	cases := make([]reflect.SelectCase, 0, len(channels)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(stop)})
+	for _, c := range channels {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)})
+	}
+
+	go func() {
+		defer close(done)
+		defer close(out)
+		for open := len(channels); open > 0; {
+			i, v, ok := reflect.Select(cases)
+			if i == 0 {
+				return
+			}
+			if !ok {
+				cases[i].Chan = reflect.Value{}
+				open--
+				continue
+			}
+			select {
+			case out <- receivedValue[T](v):
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() { close(stop) })
+		<-done
+	}
+}
+
+// MergeWithStats merges channels like mergeN (Rule 66), and also returns a function reporting how many values
+// each source has forwarded so far, keyed by its index in channels.
+// The counts are atomics updated by the merge goroutine, so the function can be called while the merge runs;
+// the counts of different sources are not read at the same instant, though.
+func MergeWithStats[T any](channels ...<-chan T) (<-chan T, func() map[int]int) {
+	out := make(chan T)
+	forwarded := make([]atomic.Int64, len(channels))
+
+	cases := make([]reflect.SelectCase, len(channels))
+	for i, c := range channels {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c)}
+	}
+
+	go func() {
+		defer close(out)
+		for open := len(channels); open > 0; {
+			i, v, ok := reflect.Select(cases)
+			if !ok {
+				cases[i].Chan = reflect.Value{}
+				open--
+				continue
+			}
+			out <- receivedValue[T](v)
+			forwarded[i].Add(1)
+		}
+	}()
+
+	return out, func() map[int]int {
+		stats := make(map[int]int, len(forwarded))
+		for i := range forwarded {
+			stats[i] = int(forwarded[i].Load())
+		}
+		return stats
+	}
+}
+
+// Multiplex receives from every channel in handlers and invokes the matching handler with the value,
+// until stop is closed. Handlers run sequentially on the calling goroutine.
+//
+// select needs its cases at compile time, so a map of channels is served with reflect.Select instead.
+// A closed channel is disabled rather than removed, and Multiplex keeps waiting for stop
+// so that the caller always decides when the loop ends.
+func Multiplex(stop <-chan struct{}, handlers map[<-chan int]func(int)) {
+	cases := make([]reflect.SelectCase, 0, len(handlers)+1)
+	funcs := make([]func(int), 0, len(handlers)+1)
+
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(stop)})
+	funcs = append(funcs, nil)
+	for ch, fn := range handlers {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+		funcs = append(funcs, fn)
+	}
+
+	for {
+		chosen, value, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return
+		}
+		if !ok {
+			// reflect.Select ignores a case whose Chan is the zero Value.
+			cases[chosen].Chan = reflect.Value{}
+			continue
+		}
+		funcs[chosen](int(value.Int()))
+	}
+}
+
+// MergePriority merges high and low into a single channel, forwarding values of high first.
+// Before every value, the high channels are polled with a default case, so a backlog on high is drained
+// before anything from low is forwarded. Only when no high channel is ready does it wait on all of them.
+// The output is closed once every input is closed.
+//
+// The priority is strict: a steady flow on high starves low. And once it waits on all the channels,
+// a low value that arrives together with a high one may still be forwarded first, as select picks at random.
+func MergePriority[T any](high []<-chan T, low []<-chan T) <-chan T {
+	out := make(chan T)
+
+	cases := make([]reflect.SelectCase, 0, len(high)+len(low))
+	for _, ch := range high {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	for _, ch := range low {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	// poll holds the high cases and a default case, which makes the select return if none is ready.
+	poll := make([]reflect.SelectCase, len(high)+1)
+	copy(poll, cases[:len(high)])
+	poll[len(high)] = reflect.SelectCase{Dir: reflect.SelectDefault}
+
+	go func() {
+		defer close(out)
+
+		open, highOpen := len(cases), len(high)
+		forward := func(chosen int, value reflect.Value, ok bool) {
+			if !ok {
+				cases[chosen].Chan = reflect.Value{}
+				open--
+				if chosen < len(high) {
+					poll[chosen].Chan = reflect.Value{}
+					highOpen--
+				}
+				return
+			}
+			out <- receivedValue[T](value)
+		}
+
+		for open > 0 {
+			if highOpen > 0 {
+				if chosen, value, ok := reflect.Select(poll); chosen < len(high) {
+					forward(chosen, value, ok)
+					continue
+				}
+			}
+			forward(reflect.Select(cases))
+		}
+	}()
+	return out
+}
+
+// DynamicSelect is Multiplex over a set of channels that can change while it runs.
+// Run rebuilds its cases from the current set on every iteration, and Add and Remove wake it up
+// through the changed channel, so a new channel doesn't wait for a value on the old ones to be served.
+type DynamicSelect struct {
+	mu       sync.Mutex
+	handlers map[<-chan int]func(int)
+	changed  chan struct{}
+}
+
+func NewDynamicSelect() *DynamicSelect {
+	return &DynamicSelect{
+		handlers: make(map[<-chan int]func(int)),
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// Add starts serving ch with handler, or replaces the handler if ch is already served.
+func (d *DynamicSelect) Add(ch <-chan int, handler func(int)) {
+	d.mu.Lock()
+	d.handlers[ch] = handler
+	d.mu.Unlock()
+	d.notify()
+}
+
+// Remove stops serving ch. Once it returns, the handler of ch is not called anymore:
+// a value Run already received from ch is dropped, and a handler that is running has returned.
+func (d *DynamicSelect) Remove(ch <-chan int) {
+	d.mu.Lock()
+	delete(d.handlers, ch)
+	d.mu.Unlock()
+	d.notify()
+}
+
+// notify wakes up Run without blocking. A pending notification already covers this change.
+func (d *DynamicSelect) notify() {
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run receives from the channels of the set and invokes the matching handler with the value,
+// until ctx is cancelled, and returns ctx.Err(). A closed channel is removed from the set.
+// Handlers run sequentially on the calling goroutine while the set is locked,
+// so they must not call Add or Remove.
+func (d *DynamicSelect) Run(ctx context.Context) error {
+	for {
+		d.mu.Lock()
+		cases := make([]reflect.SelectCase, 0, len(d.handlers)+2)
+		channels := make([]<-chan int, 0, len(d.handlers))
+		cases = append(cases,
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(d.changed)},
+		)
+		for ch := range d.handlers {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+			channels = append(channels, ch)
+		}
+		d.mu.Unlock()
+
+		chosen, value, ok := reflect.Select(cases)
+		switch chosen {
+		case 0:
+			return ctx.Err()
+		case 1:
+			continue
+		}
+
+		ch := channels[chosen-2]
+		d.mu.Lock()
+		// The set may have changed while Run was waiting, so the handler is looked up again.
+		if handler, found := d.handlers[ch]; found {
+			if ok {
+				handler(int(value.Int()))
+			} else {
+				delete(d.handlers, ch)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// Tee forwards every value of in to both outputs, and closes both when in closes.
+//
+// Each value is offered to both outputs in a select, so it doesn't matter which consumer is ready first.
+// However, the next value isn't read until both outputs received the current one,
+// which means the pipeline moves at the pace of the slower consumer.
+func Tee[T any](in <-chan T) (<-chan T, <-chan T) {
+	outA := make(chan T)
+	outB := make(chan T)
+	go func() {
+		defer close(outA)
+		defer close(outB)
+
+		for v := range in {
+			a, b := outA, outB
+			for a != nil || b != nil {
+				select {
+				case a <- v:
+					a = nil
+				case b <- v:
+					b = nil
+				}
+			}
+		}
+	}()
+	return outA, outB
+}
+
+// Zip pairs the i-th value of a with the i-th value of b.
+// The output is closed as soon as either input is closed, and an unpaired value is discarded.
+// Zip stops reading the other input at that point, so its producer must be stopped separately.
+func Zip[A, B any](a <-chan A, b <-chan B) <-chan struct {
+	First  A
+	Second B
+} {
+	out := make(chan struct {
+		First  A
+		Second B
+	})
+	go func() {
+		defer close(out)
+		for {
+			first, ok := <-a
+			if !ok {
+				return
+			}
+			second, ok := <-b
+			if !ok {
+				return
+			}
+			out <- struct {
+				First  A
+				Second B
+			}{First: first, Second: second}
+		}
+	}()
+	return out
+}
+
+// Broadcaster delivers every published value to every subscriber.
+type Broadcaster[T any] struct {
+	mu     sync.RWMutex
+	subs   []chan T
+	closed bool
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewBroadcaster[T any]() *Broadcaster[T] {
+	return &Broadcaster[T]{done: make(chan struct{})}
+}
+
+// Subscribe returns a channel receiving every value published from now on.
+// The channel is closed by Close, so a subscriber can simply range over it.
+func (b *Broadcaster[T]) Subscribe(buffer int) <-chan T {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan T, buffer)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subs = append(b.subs, ch)
+	return ch
+}
+
+// Publish sends v to every subscriber, waiting for the slow ones.
+// It returns false if the broadcaster is closed before v reached every subscriber.
+//
+// Sends happen under the read lock, and Close closes the channels under the write lock,
+// so a Publish racing with Close can never send on a closed channel.
+func (b *Broadcaster[T]) Publish(v T) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return false
+	}
+	for _, ch := range b.subs {
+		select {
+		case ch <- v:
+		case <-b.done:
+			return false
+		}
+	}
+	return true
+}
+
+// Close closes every subscriber channel. It is safe to call more than once.
+// done is closed before taking the lock, so a Publish blocked on a subscriber that stopped reading
+// gives up and releases its read lock instead of blocking Close forever.
+func (b *Broadcaster[T]) Close() {
+	b.once.Do(func() {
+		close(b.done)
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.closed = true
+		for _, ch := range b.subs {
+			close(ch)
+		}
+		b.subs = nil
+	})
+}
+
+// SafeProducer owns an output channel that any number of goroutines send to, and that is closed exactly once.
+// Closing a channel while another goroutine sends on it panics, so the usual rule is that only the sender closes.
+// With several senders, none of them knows when the others are done, so SafeProducer does it for them,
+// with the same locking as Broadcaster: sends hold the read lock, and the channel is closed under the write lock.
+type SafeProducer[T any] struct {
+	mu   sync.RWMutex
+	out  chan T
+	done chan struct{}
+	once sync.Once
+}
+
+func NewSafeProducer[T any](buffer int) *SafeProducer[T] {
+	return &SafeProducer[T]{out: make(chan T, buffer), done: make(chan struct{})}
+}
+
+// Out returns the channel the values are sent on. It is closed by Close,
+// after the values still in its buffer, so a consumer can drain it with a range loop.
+func (p *SafeProducer[T]) Out() <-chan T {
+	return p.out
+}
+
+// Send sends v, waiting for the consumer if the buffer is full.
+// It returns false if the producer is closed before v was sent.
+func (p *SafeProducer[T]) Send(v T) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	// Without this check, a Send after Close could still pick the send case if there is room in the buffer.
+	select {
+	case <-p.done:
+		return false
+	default:
+	}
+	select {
+	case p.out <- v:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Close stops accepting values, waits for the Sends in progress to return, and closes the channel.
+// A Send blocked on a full buffer is woken up by done, so Close doesn't depend on the consumer.
+// It is safe to call more than once, and from several goroutines.
+func (p *SafeProducer[T]) Close() {
+	p.once.Do(func() {
+		close(p.done)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		close(p.out)
+	})
+}
+
+// MultiProducer closes its output channel once every registered producer is done.
+// Unlike SafeProducer, nothing has to decide when to close: each producer registers with Sender,
+// and the channel is closed after the last registered producer called Done, so no send can follow the close.
+//
+// Every producer must be registered before the last registered one calls Done,
+// typically by calling Sender before starting its goroutine, like WaitGroup.Add.
+type MultiProducer[T any] struct {
+	out     chan T
+	wg      sync.WaitGroup
+	closing sync.Once
+}
+
+func NewMultiProducer[T any](buffer int) *MultiProducer[T] {
+	return &MultiProducer[T]{out: make(chan T, buffer)}
+}
+
+// Out returns the channel the producers send on. It is closed after the last producer is done.
+func (p *MultiProducer[T]) Out() <-chan T {
+	return p.out
+}
+
+// Sender registers a producer, and returns the function it sends with.
+// The producer must call Done once it has sent its last value.
+func (p *MultiProducer[T]) Sender() func(T) {
+	p.wg.Add(1)
+	// The closing goroutine only starts with the first producer: waiting on a WaitGroup that is still at zero
+	// would return right away and close the channel before anything was sent.
+	p.closing.Do(func() {
+		go func() {
+			p.wg.Wait()
+			close(p.out)
+		}()
+	})
+	return func(v T) {
+		p.out <- v
+	}
+}
+
+// Done tells that one producer has sent its last value. It must be called exactly once per Sender.
+func (p *MultiProducer[T]) Done() {
+	p.wg.Done()
+}
@@ -0,0 +1,965 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGenerateRate(t *testing.T) {
+	const perSecond = 200
+	const window = 500 * time.Millisecond
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	values := GenerateRate(ctx, 0, 1, perSecond)
+	deadline := time.After(window)
+	received := 0
+loop:
+	for {
+		select {
+		case v := <-values:
+			if v != received {
+				t.Fatalf("value %d = %d, want %d", received, v, received)
+			}
+			received++
+		case <-deadline:
+			break loop
+		}
+	}
+
+	// The ticker can only drop ticks, so the upper bound is strict, and the lower one leaves room for a busy machine.
+	want := int(perSecond * window.Seconds())
+	if received > want+1 || received < want/2 {
+		t.Fatalf("received %d values in %v, want about %d", received, window, want)
+	}
+
+	cancel()
+	for range values {
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestGenerateRateLimits(t *testing.T) {
+	for _, perSecond := range []float64{0, -1, math.NaN(), math.Inf(-1)} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("GenerateRate() with perSecond %v didn't panic", perSecond)
+				}
+			}()
+			GenerateRate(context.Background(), 0, 1, perSecond)
+		}()
+	}
+
+	// Rates beyond a value per nanosecond get the shortest ticker instead of a zero interval.
+	for _, perSecond := range []float64{2e9, math.Inf(1)} {
+		baseline := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		values := GenerateRate(ctx, 0, 1, perSecond)
+		for want := 0; want < 3; want++ {
+			if v := <-values; v != want {
+				t.Fatalf("perSecond %v: value %d = %d, want %d", perSecond, want, v, want)
+			}
+		}
+		cancel()
+		for range values {
+		}
+		waitForGoroutines(t, baseline)
+	}
+
+	// A rate whose interval overflows a time.Duration emits nothing, instead of running at full speed.
+	baseline := runtime.NumGoroutine()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	received := 0
+	for range GenerateRate(ctx, 0, 1, 1e-12) {
+		received++
+	}
+	if received != 0 {
+		t.Fatalf("perSecond 1e-12: received %d values in 50ms, want 0", received)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestStageErr(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	errOdd := errors.New("odd value")
+
+	halve := func(v int) (int, error) {
+		if v%2 != 0 {
+			return 0, fmt.Errorf("halve(%d): %w", v, errOdd)
+		}
+		return v / 2, nil
+	}
+	results, errs := StageErr(FromSlice([]int{0, 1, 2, 3, 4, 5, 6}), halve)
+
+	var got []int
+	failed := 0
+	for results != nil || errs != nil {
+		select {
+		case v, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+			got = append(got, v)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if !errors.Is(err, errOdd) {
+				t.Fatalf("error = %v, want %v", err, errOdd)
+			}
+			failed++
+		}
+	}
+
+	if want := []int{0, 1, 2, 3}; !slices.Equal(got, want) {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+	if failed != 3 {
+		t.Fatalf("received %d errors, want 3", failed)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestOrderedMapChan(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	input := make([]int, 200)
+	for i := range input {
+		input[i] = i
+	}
+	var running, peak atomic.Int64
+	slowSquare := func(v int) int {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+		running.Add(-1)
+		return v * v
+	}
+
+	got := ToSlice(OrderedMapChan(FromSlice(input), 8, slowSquare))
+
+	if len(got) != len(input) {
+		t.Fatalf("OrderedMapChan() emitted %d values, want %d", len(got), len(input))
+	}
+	for i, v := range got {
+		if v != i*i {
+			t.Fatalf("value %d = %d, want %d", i, v, i*i)
+		}
+	}
+	if peak.Load() > 8 {
+		t.Fatalf("fn ran %d times at once, want at most 8", peak.Load())
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestFromSliceToSlice(t *testing.T) {
+	for _, testcase := range []struct {
+		name  string
+		input []int
+	}{
+		{name: "Empty", input: []int{}},
+		{name: "One", input: []int{1}},
+		{name: "Many", input: []int{3, 1, 4, 1, 5, 9, 2, 6}},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			got := ToSlice(FromSlice(testcase.input))
+
+			if len(got) != len(testcase.input) {
+				t.Fatalf("ToSlice(FromSlice(%v)) has %d elements, want %d", testcase.input, len(got), len(testcase.input))
+			}
+			for i := range got {
+				if got[i] != testcase.input[i] {
+					t.Fatalf("ToSlice(FromSlice(%v)) = %v", testcase.input, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReduceByKey(t *testing.T) {
+	type pair = struct {
+		Key string
+		Val int
+	}
+	words := strings.Fields("the quick brown fox jumps over the lazy dog the end")
+	pairs := make([]pair, len(words))
+	for i, word := range words {
+		pairs[i] = pair{Key: word[:1], Val: len(word)}
+	}
+
+	// Total length and word list per first letter, computed directly as the reference.
+	wantLengths := make(map[string]int)
+	wantWords := make(map[string][]int)
+	for _, p := range pairs {
+		wantLengths[p.Key] += p.Val
+		wantWords[p.Key] = append(wantWords[p.Key], p.Val)
+	}
+
+	lengths := ReduceByKey(FromSlice(pairs), func(acc, v int) int { return acc + v }, func() int { return 0 })
+	if !maps.Equal(lengths, wantLengths) {
+		t.Fatalf("ReduceByKey(sum) = %v, want %v", lengths, wantLengths)
+	}
+
+	// The aggregate can be of another type than the values, and initial gives every key its own.
+	lists := ReduceByKey(FromSlice(pairs), func(acc []int, v int) []int { return append(acc, v) }, func() []int { return nil })
+	if !maps.EqualFunc(lists, wantWords, slices.Equal) {
+		t.Fatalf("ReduceByKey(append) = %v, want %v", lists, wantWords)
+	}
+
+	if empty := ReduceByKey(FromSlice([]pair(nil)), func(acc, v int) int { return acc + v }, func() int { return 0 }); len(empty) != 0 {
+		t.Fatalf("ReduceByKey(empty) = %v, want an empty map", empty)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	isEven := func(v int) bool { return v%2 == 0 }
+
+	got := ToSlice(Filter(Take(Generate(ctx, 0, 1), 10), isEven))
+	// Take stopped reading, so the generator has to be cancelled.
+	cancel()
+
+	if want := []int{0, 2, 4, 6, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Filter() = %v, want %v", got, want)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestMap(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	got := ToSlice(Map(FromSlice([]int{1, 2, 3}), func(v int) string { return strings.Repeat("a", v) }))
+
+	if want := []string{"a", "aa", "aaa"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Map() = %v, want %v", got, want)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestCollect(t *testing.T) {
+	t.Run("Limit", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		got := Collect(ctx, Generate(ctx, 1, 1), 3)
+		cancel()
+
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Collect() = %v, want %v", got, want)
+		}
+		waitForGoroutines(t, baseline)
+	})
+
+	t.Run("InputClosed", func(t *testing.T) {
+		got := Collect(context.Background(), FromSlice([]int{1, 2}), 0)
+
+		if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Collect() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		in := make(chan int)
+		release := make(chan struct{})
+		go func() {
+			defer close(in)
+			in <- 1
+			in <- 2
+			cancel()
+			// Keep in open, so only the cancellation can stop Collect.
+			<-release
+		}()
+
+		got := Collect(ctx, in, 0)
+		close(release)
+
+		if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Collect() = %v, want %v", got, want)
+		}
+		waitForGoroutines(t, baseline)
+	})
+}
+
+func TestBatch(t *testing.T) {
+	for _, testcase := range []struct {
+		name  string
+		input []int
+		size  int
+		wants [][]int
+	}{
+		{
+			name:  "ZeroItems",
+			input: nil,
+			size:  3,
+			wants: nil,
+		},
+		{
+			name:  "ExactMultiple",
+			input: []int{1, 2, 3, 4, 5, 6},
+			size:  3,
+			wants: [][]int{{1, 2, 3}, {4, 5, 6}},
+		},
+		{
+			name:  "PartialFinal",
+			input: []int{1, 2, 3, 4, 5},
+			size:  2,
+			wants: [][]int{{1, 2}, {3, 4}, {5}},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			baseline := runtime.NumGoroutine()
+
+			got := ToSlice(Batch(FromSlice(testcase.input), testcase.size, time.Hour))
+
+			if !reflect.DeepEqual(got, testcase.wants) {
+				t.Fatalf("Batch() = %v, want %v", got, testcase.wants)
+			}
+			waitForGoroutines(t, baseline)
+		})
+	}
+}
+
+func TestBatchProcess(t *testing.T) {
+	var batches [][]int
+	err := BatchProcess(context.Background(), FromSlice([]int{1, 2, 3, 4, 5}), 2, time.Hour, func(batch []int) error {
+		batches = append(batches, batch)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("BatchProcess() = %v, want nil", err)
+	}
+	if want := [][]int{{1, 2}, {3, 4}, {5}}; !reflect.DeepEqual(batches, want) {
+		t.Fatalf("flushed %v, want %v", batches, want)
+	}
+}
+
+func TestBatchProcessJoinsFlushErrors(t *testing.T) {
+	errFirst := errors.New("first batch failed")
+	errThird := errors.New("third batch failed")
+
+	var flushed [][]int
+	err := BatchProcess(context.Background(), FromSlice([]int{1, 2, 3, 4, 5}), 2, time.Hour, func(batch []int) error {
+		flushed = append(flushed, batch)
+		switch batch[0] {
+		case 1:
+			return errFirst
+		case 5:
+			return errThird
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errFirst) || !errors.Is(err, errThird) {
+		t.Fatalf("BatchProcess() = %v, want both %v and %v", err, errFirst, errThird)
+	}
+	// A failed flush doesn't stop the processing.
+	if len(flushed) != 3 {
+		t.Fatalf("flushed %d batches, want 3", len(flushed))
+	}
+}
+
+func TestBatchProcessCancelled(t *testing.T) {
+	in := make(chan int)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var batches [][]int
+	errc := make(chan error)
+	go func() {
+		errc <- BatchProcess(ctx, in, 3, time.Hour, func(batch []int) error {
+			batches = append(batches, batch)
+			return nil
+		})
+	}()
+
+	for v := 1; v <= 4; v++ {
+		in <- v
+	}
+	// in is never closed, the partial batch is flushed because ctx is cancelled.
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("BatchProcess() = %v, want %v", err, context.Canceled)
+	}
+	if want := [][]int{{1, 2, 3}, {4}}; !reflect.DeepEqual(batches, want) {
+		t.Fatalf("flushed %v, want %v", batches, want)
+	}
+}
+
+func TestBatchProcessFlushesAfterMaxWait(t *testing.T) {
+	in := make(chan int)
+	flushed := make(chan []int)
+	go func() {
+		_ = BatchProcess(context.Background(), in, 10, 10*time.Millisecond, func(batch []int) error {
+			flushed <- batch
+			return nil
+		})
+	}()
+
+	in <- 1
+	select {
+	case batch := <-flushed:
+		if !reflect.DeepEqual(batch, []int{1}) {
+			t.Fatalf("batch = %v, want [1]", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("partial batch was not flushed after maxWait")
+	}
+	close(in)
+}
+
+func TestFlattenBatch(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got := ToSlice(Flatten(Batch(Take(Generate(ctx, 0, 1), 25), 4, time.Hour)))
+	cancel()
+
+	if len(got) != 25 {
+		t.Fatalf("Flatten(Batch()) emitted %d values, want 25", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("Flatten(Batch()) = %v, want 0..24 in order", got)
+		}
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestBatchFlushesAfterMaxWait(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	in := make(chan int)
+	out := Batch(in, 10, 10*time.Millisecond)
+
+	in <- 1
+	in <- 2
+	select {
+	case batch := <-out:
+		if !reflect.DeepEqual(batch, []int{1, 2}) {
+			t.Fatalf("batch = %v, want [1 2]", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("partial batch was not flushed after maxWait")
+	}
+
+	close(in)
+	if batch, ok := <-out; ok {
+		t.Fatalf("received %v after close, want closed output", batch)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestMergeStoppable(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	merged, stop := MergeStoppable(Generate(ctx, 0, 2), Generate(ctx, 1, 2))
+
+	for i := 0; i < 5; i++ {
+		<-merged
+	}
+	// Both generators keep producing, so the merge goroutine is blocked sending the next value.
+	stop()
+	stop()
+
+	if _, ok := <-merged; ok {
+		t.Fatal("received a value after stop, want the output to be closed")
+	}
+	cancel()
+	waitForGoroutines(t, baseline)
+}
+
+func TestMergeStoppableAllClosed(t *testing.T) {
+	merged, stop := MergeStoppable(FromSlice([]string{"a", "b"}), FromSlice([]string{"c"}))
+	defer stop()
+
+	got := ToSlice(merged)
+	slices.Sort(got)
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("MergeStoppable() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeStoppableNilInterface(t *testing.T) {
+	errFailed := errors.New("failed")
+	merged, stop := MergeStoppable(FromSlice([]error{nil, errFailed}))
+	defer stop()
+
+	if got := ToSlice(merged); len(got) != 2 || got[0] != nil || got[1] != errFailed {
+		t.Fatalf("MergeStoppable() = %v, want [<nil> %v]", got, errFailed)
+	}
+}
+
+func TestMergeWithStats(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	merged, stats := MergeWithStats(produce(1, 2, 3, 4, 5), produce(6, 7), produce())
+
+	// Reading the stats while the merge runs is safe.
+	received := 0
+	for range merged {
+		received++
+		stats()
+	}
+
+	if received != 7 {
+		t.Fatalf("received %d values, want 7", received)
+	}
+	if got, want := stats(), map[int]int{0: 5, 1: 2, 2: 0}; !maps.Equal(got, want) {
+		t.Fatalf("stats() = %v, want %v", got, want)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestMergeWithStatsNilInterface(t *testing.T) {
+	merged, stats := MergeWithStats(FromSlice([]error{nil, nil}))
+
+	if got := ToSlice(merged); len(got) != 2 || got[0] != nil || got[1] != nil {
+		t.Fatalf("MergeWithStats() = %v, want [<nil> <nil>]", got)
+	}
+	if got, want := stats(), map[int]int{0: 2}; !maps.Equal(got, want) {
+		t.Fatalf("stats() = %v, want %v", got, want)
+	}
+}
+
+func TestMultiplex(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	a := make(chan int)
+	b := make(chan int)
+	var countA, sumB int
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		Multiplex(stop, map[<-chan int]func(int){
+			a: func(int) { countA++ },
+			b: func(v int) { sumB += v },
+		})
+		close(done)
+	}()
+
+	for i := 1; i <= 5; i++ {
+		a <- i
+		b <- i
+	}
+	a <- 6
+	close(b)
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Multiplex did not return after stop was closed")
+	}
+
+	if countA != 6 {
+		t.Fatalf("handler for a called %d times, want 6", countA)
+	}
+	if sumB != 15 {
+		t.Fatalf("handler for b received sum %d, want 15", sumB)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestMergePriorityDrainsHighFirst(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	backlog := func(values ...int) <-chan int {
+		ch := make(chan int, len(values))
+		for _, v := range values {
+			ch <- v
+		}
+		close(ch)
+		return ch
+	}
+	high := []<-chan int{backlog(1, 2, 3), backlog(4, 5)}
+	low := []<-chan int{backlog(101, 102, 103), backlog(104)}
+
+	got := ToSlice(MergePriority(high, low))
+
+	if len(got) != 9 {
+		t.Fatalf("received %v, want 9 values", got)
+	}
+	// mergeN would pick among the ready channels at random, and interleave both tiers.
+	for i, v := range got {
+		if isHigh := v < 100; isHigh != (i < 5) {
+			t.Fatalf("received %v, want the 5 high-priority values before the low-priority ones", got)
+		}
+	}
+	// Within a tier, every channel keeps its own order.
+	if high := slices.DeleteFunc(slices.Clone(got[:5]), func(v int) bool { return v > 3 }); !slices.Equal(high, []int{1, 2, 3}) {
+		t.Fatalf("received %v from the first high channel, want [1 2 3]", high)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestMergePriorityLiveInputs(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	high := []<-chan string{FromSlice([]string{"a", "b"})}
+	low := []<-chan string{FromSlice([]string{"x", "y", "z"}), FromSlice[string](nil)}
+
+	got := ToSlice(MergePriority(high, low))
+
+	slices.Sort(got)
+	if want := []string{"a", "b", "x", "y", "z"}; !slices.Equal(got, want) {
+		t.Fatalf("received %v, want %v", got, want)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestDynamicSelect(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	d := NewDynamicSelect()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errc := make(chan error)
+	go func() {
+		errc <- d.Run(ctx)
+	}()
+
+	// Run is already waiting on an empty set when a is added.
+	a := make(chan int)
+	var countA, sumA atomic.Int64
+	d.Add(a, func(v int) {
+		countA.Add(1)
+		sumA.Add(int64(v))
+	})
+	for i := 1; i <= 3; i++ {
+		a <- i
+	}
+	// A send completes when Run receives the value, before the handler is called.
+	for countA.Load() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	d.Remove(a)
+	// Run may still be waiting in a select that was built before the removal and receive this value,
+	// but it must drop it instead of calling the handler.
+	select {
+	case a <- 4:
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	b := make(chan int)
+	var sumB atomic.Int64
+	d.Add(b, func(v int) { sumB.Add(int64(v)) })
+	b <- 10
+	for sumB.Load() != 10 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Run serves one value at a time, so a value it received from a is handled by now.
+	if n, sum := countA.Load(), sumA.Load(); n != 3 || sum != 6 {
+		t.Fatalf("handler for a called %d times with sum %d, want 3 times with sum 6", n, sum)
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v, want %v", err, context.Canceled)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestDynamicSelectRemovesClosedChannel(t *testing.T) {
+	d := NewDynamicSelect()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- d.Run(ctx)
+	}()
+
+	ch := make(chan int)
+	d.Add(ch, func(int) {})
+	close(ch)
+	for {
+		d.mu.Lock()
+		n := len(d.handlers)
+		d.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestTee(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	input := []int{1, 2, 3, 4, 5}
+	a, b := Tee(FromSlice(input))
+
+	var gotA, gotB []int
+	done := make(chan struct{})
+	go func() {
+		for v := range a {
+			gotA = append(gotA, v)
+		}
+		close(done)
+	}()
+	for v := range b {
+		// The slower consumer only slows the other one down, it doesn't deadlock it.
+		time.Sleep(time.Millisecond)
+		gotB = append(gotB, v)
+	}
+	<-done
+
+	if !reflect.DeepEqual(gotA, input) {
+		t.Fatalf("first output = %v, want %v", gotA, input)
+	}
+	if !reflect.DeepEqual(gotB, input) {
+		t.Fatalf("second output = %v, want %v", gotB, input)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestZip(t *testing.T) {
+	for _, testcase := range []struct {
+		name   string
+		first  []int
+		second []string
+		wants  int
+	}{
+		{
+			name:   "EqualLength",
+			first:  []int{1, 2, 3},
+			second: []string{"a", "b", "c"},
+			wants:  3,
+		},
+		{
+			name:   "FirstShorter",
+			first:  []int{1, 2},
+			second: []string{"a", "b", "c"},
+			wants:  2,
+		},
+		{
+			name:   "SecondShorter",
+			first:  []int{1, 2, 3},
+			second: []string{"a"},
+			wants:  1,
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			baseline := runtime.NumGoroutine()
+
+			n := 0
+			for pair := range Zip(FromSlice(testcase.first), FromSlice(testcase.second)) {
+				if pair.First != testcase.first[n] || pair.Second != testcase.second[n] {
+					t.Fatalf("pair %d = %v, want {%d %s}", n, pair, testcase.first[n], testcase.second[n])
+				}
+				n++
+			}
+
+			if n != testcase.wants {
+				t.Fatalf("Zip() emitted %d pairs, want %d", n, testcase.wants)
+			}
+			waitForGoroutines(t, baseline)
+		})
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	b := NewBroadcaster[int]()
+	first := b.Subscribe(0)
+	second := b.Subscribe(3)
+
+	var gotFirst []int
+	done := make(chan struct{})
+	go func() {
+		for v := range first {
+			gotFirst = append(gotFirst, v)
+		}
+		close(done)
+	}()
+
+	for i := 1; i <= 3; i++ {
+		if !b.Publish(i) {
+			t.Fatalf("Publish(%d) = false, want true", i)
+		}
+	}
+	b.Close()
+	<-done
+
+	gotSecond := Collect(context.Background(), second, 0)
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(gotFirst, want) || !reflect.DeepEqual(gotSecond, want) {
+		t.Fatalf("subscribers received %v and %v, want %v", gotFirst, gotSecond, want)
+	}
+	if b.Publish(4) {
+		t.Fatal("Publish() after Close = true, want false")
+	}
+	if _, ok := <-b.Subscribe(1); ok {
+		t.Fatal("Subscribe() after Close returned an open channel")
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestBroadcasterPublishRacingClose(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	for run := 0; run < 100; run++ {
+		b := NewBroadcaster[int]()
+
+		subscribers := sync.WaitGroup{}
+		subscribers.Add(4)
+		for i := 0; i < 4; i++ {
+			ch := b.Subscribe(i)
+			go func() {
+				defer subscribers.Done()
+				for range ch {
+				}
+			}()
+		}
+		// A subscriber that never reads must not keep Close from returning.
+		b.Subscribe(0)
+
+		publishers := sync.WaitGroup{}
+		publishers.Add(4)
+		for i := 0; i < 4; i++ {
+			go func() {
+				defer publishers.Done()
+				for j := 0; b.Publish(j); j++ {
+				}
+			}()
+		}
+
+		b.Close()
+		publishers.Wait()
+		subscribers.Wait()
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestSafeProducerSendRacingClose(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	for run := 0; run < 100; run++ {
+		p := NewSafeProducer[int](run % 4)
+
+		var sent atomic.Int64
+		producers := sync.WaitGroup{}
+		producers.Add(4)
+		for i := 0; i < 4; i++ {
+			go func() {
+				defer producers.Done()
+				for j := 0; p.Send(j); j++ {
+					sent.Add(1)
+				}
+			}()
+		}
+
+		received := make(chan int64)
+		go func() {
+			var n int64
+			for range p.Out() {
+				n++
+			}
+			received <- n
+		}()
+
+		// Close races with the Sends, and a second Close with the first one.
+		go p.Close()
+		p.Close()
+		producers.Wait()
+
+		// Every value Send reported as sent is delivered before the channel is closed.
+		if n := <-received; n != sent.Load() {
+			t.Fatalf("received %d values, want %d", n, sent.Load())
+		}
+		if p.Send(1) {
+			t.Fatal("Send() after Close = true, want false")
+		}
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestSafeProducerCloseWithoutConsumer(t *testing.T) {
+	p := NewSafeProducer[int](1)
+	if !p.Send(1) {
+		t.Fatal("Send() into an empty buffer = false, want true")
+	}
+
+	blocked := make(chan bool)
+	go func() {
+		blocked <- p.Send(2)
+	}()
+
+	// Close doesn't wait for a consumer to unblock the Send on a full buffer.
+	p.Close()
+	if <-blocked {
+		t.Fatal("Send() blocked on a full buffer = true after Close, want false")
+	}
+	if got := ToSlice(p.Out()); !slices.Equal(got, []int{1}) {
+		t.Fatalf("drained %v, want [1]", got)
+	}
+}
+
+func TestMultiProducer(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	p := NewMultiProducer[int](1)
+	var finished atomic.Int32
+	for producer := 1; producer <= 3; producer++ {
+		send := p.Sender()
+		go func() {
+			defer p.Done()
+			defer finished.Add(1)
+			for v := 0; v < 10; v++ {
+				send(producer)
+			}
+			// The producers finish at different times, the last one well after the others.
+			time.Sleep(time.Duration(producer*producer) * time.Millisecond)
+		}()
+	}
+
+	sum := 0
+	for v := range p.Out() {
+		sum += v
+	}
+
+	if n := finished.Load(); n != 3 {
+		t.Fatalf("Out closed after %d producers finished, want 3", n)
+	}
+	if want := 10 * (1 + 2 + 3); sum != want {
+		t.Fatalf("received sum %d, want %d", sum, want)
+	}
+	waitForGoroutines(t, baseline)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// pprofShutdownTimeout bounds how long a cancelled pprof server waits for running requests.
+// A CPU profile request blocks for 30 seconds by default, so those are cut off instead of waited for.
+const pprofShutdownTimeout = 5 * time.Second
+
+// peakSampleInterval is how often MeasurePeakGoroutines counts the goroutines.
+const peakSampleInterval = 50 * time.Microsecond
+
+// servePprof serves the net/http/pprof handlers on addr until ctx is cancelled.
+// The handlers are registered on a separate mux instead of http.DefaultServeMux,
+// so they are only reachable through this server.
+// It returns the address the server listens on, which is useful with port 0,
+// and a channel that receives the result of the shutdown once the server goroutine has exited.
+func servePprof(ctx context.Context, addr string) (net.Addr, <-chan error, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case err := <-served:
+			// Serve stopped on its own, e.g. because the listener failed.
+			done <- err
+			return
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), pprofShutdownTimeout)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = server.Close()
+		}
+		// Serve returns ErrServerClosed as soon as Shutdown is called, so it is only waited for here.
+		<-served
+		done <- err
+	}()
+
+	return listener.Addr(), done, nil
+}
+
+// MeasurePeakGoroutines runs fn, and returns the highest number of goroutines observed meanwhile,
+// above the number that was running before fn (the sampler itself isn't counted).
+// It is a sampling measurement: a burst of goroutines shorter than the interval may be missed,
+// and on a busy machine the sampler may be scheduled less often than peakSampleInterval.
+// The sampler goroutine has exited when it returns.
+func MeasurePeakGoroutines(fn func()) int {
+	baseline := runtime.NumGoroutine()
+	stop := make(chan struct{})
+	peak := make(chan int)
+
+	go func() {
+		ticker := time.NewTicker(peakSampleInterval)
+		defer ticker.Stop()
+
+		highest := 0
+		for {
+			highest = max(highest, runtime.NumGoroutine())
+			select {
+			case <-stop:
+				peak <- highest
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	fn()
+	close(stop)
+	return max(<-peak-baseline-1, 0)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServePprof(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, done, err := servePprof(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("servePprof() error = %v", err)
+	}
+
+	// Keep-alive connections would leave the client's goroutines behind.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr.String() + "/debug/pprof/")
+	if err != nil {
+		t.Fatalf("GET /debug/pprof/ error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /debug/pprof/ status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("pprof server shutdown error = %v, want nil", err)
+		}
+	case <-time.After(pprofShutdownTimeout + time.Second):
+		t.Fatal("pprof server did not stop after the context was cancelled")
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestServePprofInvalidAddr(t *testing.T) {
+	if _, _, err := servePprof(context.Background(), "invalid address"); err == nil {
+		t.Fatal("servePprof() error = nil, want an error for an invalid address")
+	}
+}
+
+func TestMeasurePeakGoroutines(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	const goroutines = 50
+	peak := MeasurePeakGoroutines(func() {
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(goroutines)
+		for range goroutines {
+			go func() {
+				defer wg.Done()
+				<-release
+			}()
+		}
+		// Long enough for the sampler to run at least once while they are all blocked.
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+	})
+
+	if peak < goroutines {
+		t.Fatalf("MeasurePeakGoroutines() = %d, want at least %d", peak, goroutines)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestMeasurePeakGoroutinesNone(t *testing.T) {
+	if peak := MeasurePeakGoroutines(func() { time.Sleep(time.Millisecond) }); peak != 0 {
+		t.Fatalf("MeasurePeakGoroutines() = %d for a function without goroutines, want 0", peak)
+	}
+}
@@ -1,11 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Sink receives the results of every benchmark in this package.
+// If a result is never used, the compiler is free to eliminate the computation we're trying to measure.
+// Storing it into a package-level variable through an atomic store is an observable side effect,
+// so the work can't be optimized away.
+//
+//nolint:gochecknoglobals // must be package level to defeat dead-code elimination
+var Sink int64
+
+// Consume writes v to Sink.
+func Consume(v int64) {
+	atomic.StoreInt64(&Sink, v)
+}
+
 type Bar struct {
 	a []int64
 	b []int64
@@ -32,6 +49,49 @@ func sumFoo(foo []Foo) int64 {
 	return sum
 }
 
+// sumFooPtrSlice does the same work as sumFoo over a slice of pointers.
+// Every element costs an extra load to follow its pointer, and the Foos live wherever they were allocated,
+// so the hardware prefetcher can't stream them like the contiguous elements of a []Foo.
+// How much slower it gets depends on how scattered the Foos are in memory.
+func sumFooPtrSlice(foo []*Foo) int64 {
+	var sum int64
+	for i := 0; i < len(foo); i++ {
+		sum += foo[i].a
+	}
+	return sum
+}
+
+// computeRounds is the number of dependent multiply-adds done per element by the *Compute variants.
+const computeRounds = 8
+
+func mix(x int64) int64 {
+	for r := 0; r < computeRounds; r++ {
+		x = x*31 + 7
+	}
+	return x
+}
+
+// sumFooCompute does the same traversal as sumFoo, but spends a chain of multiplies on every element.
+// sumFoo is memory-bound: the CPU mostly waits for cache lines, and half of each line is the unused b field.
+// Here the arithmetic takes longer than fetching the data, so the loop becomes compute-bound,
+// and the layout of the data matters much less.
+func sumFooCompute(foo []Foo) int64 {
+	var sum int64
+	for i := 0; i < len(foo); i++ {
+		sum += mix(foo[i].a)
+	}
+	return sum
+}
+
+// sumBarCompute is the struct-of-arrays counterpart of sumFooCompute.
+func sumBarCompute(bar Bar) int64 {
+	var sum int64
+	for i := 0; i < len(bar.a); i++ {
+		sum += mix(bar.a[i])
+	}
+	return sum
+}
+
 var pool = sync.Pool{
 	New: func() interface{} {
 		return make([]Foo, 1024)
@@ -45,11 +105,57 @@ func fooFunction() {
 	defer pool.Put(foo)
 }
 
-// SimpleBenchmark runs a simple performance comparison between sumFoo and sumBar
-func SimpleBenchmark() {
-	const size = 200000
-	const iterations = 10000
+// fooFunctionNoDefer is fooFunction with pool.Put called directly at the end.
+// It's only equivalent as long as nothing in between can return early or panic.
+func fooFunctionNoDefer() {
+	foo := pool.Get().([]Foo)
+	foo[0].a = 1
+	foo[0].b = 2
+	pool.Put(foo)
+}
+
+// Number is the set of types SumGeneric can add up.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// SumGeneric is compiled per underlying type shape, so values stay unboxed in the slice.
+func SumGeneric[T Number](values []T) T {
+	var sum T
+	for i := 0; i < len(values); i++ {
+		sum += values[i]
+	}
+	return sum
+}
+
+// SumIface sums int64 values stored in interfaces, like the values we get back from pool.Get.
+// Storing a non-pointer value in an interface boxes it: the interface holds a pointer to a copy,
+// and unless the compiler can prove the copy doesn't escape, that copy lives on the heap.
+// Every element then costs an allocation to box, and a type assertion plus an indirection to read.
+func SumIface(values []interface{}) int64 {
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		sum += values[i].(int64)
+	}
+	return sum
+}
+
+func boxInt64s(values []int64) []interface{} {
+	boxed := make([]interface{}, len(values))
+	for i := 0; i < len(values); i++ {
+		boxed[i] = values[i]
+	}
+	return boxed
+}
+
+// SimpleBenchmark runs a simple performance comparison between sumFoo and sumBar, and writes the report to w.
+func SimpleBenchmark(w io.Writer) {
+	simpleBenchmark(w, 200000, 10000)
+}
 
+func simpleBenchmark(w io.Writer, size, iterations int) {
 	// Setup data for sumFoo
 	fooSlice := make([]Foo, size)
 	for i := 0; i < size; i++ {
@@ -66,53 +172,61 @@ func SimpleBenchmark() {
 		bar.b[i] = int64(i * 2)
 	}
 
-	fmt.Printf("Performance Comparison: sumFoo vs sumBar\n")
-	fmt.Printf("Dataset size: %d elements\n", size)
-	fmt.Printf("Iterations: %d\n\n", iterations)
+	fmt.Fprintf(w, "Performance Comparison: sumFoo vs sumBar\n")
+	fmt.Fprintf(w, "Dataset size: %d elements\n", size)
+	fmt.Fprintf(w, "Iterations: %d\n\n", iterations)
 
 	// Benchmark sumFoo
+	roundsFoo := newRoundTimer(iterations)
 	start := time.Now()
 	var resultFoo int64
 	for i := 0; i < iterations; i++ {
 		resultFoo = sumFoo(fooSlice)
+		Consume(resultFoo)
+		roundsFoo.tick()
 	}
 	durationFoo := time.Since(start)
 
 	// Benchmark sumBar
+	roundsBar := newRoundTimer(iterations)
 	start = time.Now()
 	var resultBar int64
 	for i := 0; i < iterations; i++ {
 		resultBar = sumBar(bar)
+		Consume(resultBar)
+		roundsBar.tick()
 	}
 	durationBar := time.Since(start)
 
 	// Display results
-	fmt.Printf("sumFoo Results:\n")
-	fmt.Printf("  Result: %d\n", resultFoo)
-	fmt.Printf("  Total time: %v\n", durationFoo)
-	fmt.Printf("  Average per operation: %v\n", durationFoo/time.Duration(iterations))
-
-	fmt.Printf("\nsumBar Results:\n")
-	fmt.Printf("  Result: %d\n", resultBar)
-	fmt.Printf("  Total time: %v\n", durationBar)
-	fmt.Printf("  Average per operation: %v\n", durationBar/time.Duration(iterations))
+	fmt.Fprintf(w, "sumFoo Results:\n")
+	fmt.Fprintf(w, "  Result: %d\n", resultFoo)
+	fmt.Fprintf(w, "  Total time: %v\n", durationFoo)
+	fmt.Fprintf(w, "  Average per operation: %v\n", durationFoo/time.Duration(iterations))
+	fmt.Fprintf(w, "  Std dev across rounds: %v\n", roundsFoo.stdDev())
+
+	fmt.Fprintf(w, "\nsumBar Results:\n")
+	fmt.Fprintf(w, "  Result: %d\n", resultBar)
+	fmt.Fprintf(w, "  Total time: %v\n", durationBar)
+	fmt.Fprintf(w, "  Average per operation: %v\n", durationBar/time.Duration(iterations))
+	fmt.Fprintf(w, "  Std dev across rounds: %v\n", roundsBar.stdDev())
 
 	// Performance comparison
 	if durationFoo < durationBar {
 		ratio := float64(durationBar) / float64(durationFoo)
-		fmt.Printf("\nsumFoo is %.2fx faster than sumBar\n", ratio)
+		fmt.Fprintf(w, "\nsumFoo is %.2fx faster than sumBar\n", ratio)
 	} else if durationBar < durationFoo {
 		ratio := float64(durationFoo) / float64(durationBar)
-		fmt.Printf("\nsumBar is %.2fx faster than sumFoo\n", ratio)
+		fmt.Fprintf(w, "\nsumBar is %.2fx faster than sumFoo\n", ratio)
 	} else {
-		fmt.Printf("\n Both functions have similar performance\n")
+		fmt.Fprintf(w, "\n Both functions have similar performance\n")
 	}
 
 	// Verify results are the same
 	if resultFoo == resultBar {
-		fmt.Printf("Both functions produce the same result: %d\n", resultFoo)
+		fmt.Fprintf(w, "Both functions produce the same result: %d\n", resultFoo)
 	} else {
-		fmt.Printf("Results differ: sumFoo=%d, sumBar=%d\n", resultFoo, resultBar)
+		fmt.Fprintf(w, "Results differ: sumFoo=%d, sumBar=%d\n", resultFoo, resultBar)
 	}
 }
 
@@ -132,6 +246,35 @@ type FastResult struct {
 	sumB int64
 }
 
+// sumPair is implemented by every result type of the count family,
+// so results can be compared no matter how their fields are laid out.
+type sumPair interface {
+	sums() (int64, int64)
+}
+
+func (r Result) sums() (int64, int64) {
+	return r.sumA, r.sumB
+}
+
+func (r FastResult) sums() (int64, int64) {
+	return r.sumA, r.sumB
+}
+
+// EqualSums reports whether a and b hold the same sums, even when they are of different types.
+func EqualSums[A, B sumPair](a A, b B) bool {
+	aA, aB := a.sums()
+	bA, bB := b.sums()
+	return aA == bA && aB == bB
+}
+
+func ResultsEqual(a, b Result) bool {
+	return EqualSums(a, b)
+}
+
+func FastResultsEqual(a, b FastResult) bool {
+	return EqualSums(a, b)
+}
+
 func count(inputs []Input) Result {
 	wg := sync.WaitGroup{}
 	wg.Add(2)
@@ -156,6 +299,81 @@ func count(inputs []Input) Result {
 	return result
 }
 
+// sumChecked adds field of every input to *sum, and calls check every ctxCheckInterval elements,
+// as a CPU-bound loop never blocks on anything that would notice a cancellation.
+// It stops with the error of check as soon as check returns one.
+func sumChecked(inputs []Input, sum *int64, field func(*Input) int64, check func() error) error {
+	for i := 0; i < len(inputs); i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := check(); err != nil {
+				return err
+			}
+		}
+		*sum += field(&inputs[i])
+	}
+	return nil
+}
+
+// countTimeout is count with a bound on the wait for its goroutines.
+// It reports false if they didn't finish within d, in which case the partial sums are dropped.
+// The goroutines are then cancelled, so they stop at their next check, every ctxCheckInterval elements,
+// instead of summing on in the background.
+func countTimeout(inputs []Input, d time.Duration) (Result, bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	result := Result{}
+
+	go func() {
+		defer wg.Done()
+		_ = sumChecked(inputs, &result.sumA, func(in *Input) int64 { return in.a }, ctx.Err)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_ = sumChecked(inputs, &result.sumB, func(in *Input) int64 { return in.b }, ctx.Err)
+	}()
+
+	if !JoinWithTimeout(&wg, d) {
+		return Result{}, false
+	}
+	return result, true
+}
+
+// countCtx is count that can be cancelled. Like AggregateInputs, each goroutine checks ctx every
+// ctxCheckInterval elements, with sumChecked.
+// It waits for both goroutines before returning, so a cancelled countCtx leaves nothing running,
+// and returns ctx.Err() if either of them stopped early.
+func countCtx(ctx context.Context, inputs []Input) (Result, error) {
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	result := Result{}
+	var errA, errB error
+
+	go func() {
+		defer wg.Done()
+		errA = sumChecked(inputs, &result.sumA, func(in *Input) int64 { return in.a }, ctx.Err)
+	}()
+
+	go func() {
+		defer wg.Done()
+		errB = sumChecked(inputs, &result.sumB, func(in *Input) int64 { return in.b }, ctx.Err)
+	}()
+
+	wg.Wait()
+	if errA != nil {
+		return Result{}, errA
+	}
+	if errB != nil {
+		return Result{}, errB
+	}
+	return result, nil
+}
+
 // countFast does the same work as count but writes into a padded FastResult
 // to minimize false sharing between goroutines updating sumA and sumB.
 func countFast(inputs []Input) FastResult {
@@ -182,61 +400,351 @@ func countFast(inputs []Input) FastResult {
 	return result
 }
 
-// CountBenchmark compares performance of count (Result) vs countFast (FastResult).
-func CountBenchmark() {
-	const size = 200000
-	const iterations = 50000
+// countFastTimeout is countFast with a bound on the wait for its goroutines, like countTimeout.
+func countFastTimeout(inputs []Input, d time.Duration) (FastResult, bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	result := FastResult{}
+
+	go func() {
+		defer wg.Done()
+		_ = sumChecked(inputs, &result.sumA, func(in *Input) int64 { return in.a }, ctx.Err)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_ = sumChecked(inputs, &result.sumB, func(in *Input) int64 { return in.b }, ctx.Err)
+	}()
+
+	if !JoinWithTimeout(&wg, d) {
+		return FastResult{}, false
+	}
+	return result, true
+}
+
+// countSeparate does the same work as count, but each goroutine adds into its own heap-allocated counter
+// instead of into a field of a shared struct.
+// Separate allocations are not guaranteed to land on separate cache lines: the runtime hands out
+// small objects of the same size class one after the other from the same span, so a and b usually sit
+// a few bytes apart, and suffer from the same false sharing as Result.
+// Padding (countFast) is the remedy that doesn't depend on the allocator.
+func countSeparate(inputs []Input) (a, b *int64) {
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	a, b = new(int64), new(int64)
 
+	go func() {
+		for i := 0; i < len(inputs); i++ {
+			*a += inputs[i].a
+		}
+		wg.Done()
+	}()
+
+	go func() {
+		for i := 0; i < len(inputs); i++ {
+			*b += inputs[i].b
+		}
+		wg.Done()
+	}()
+
+	wg.Wait()
+	return a, b
+}
+
+// countChan does the same work as count, but each goroutine sums into a local variable
+// and hands its partial sum over a channel instead of writing into a shared Result, so there is no false sharing.
+// With buffered, the channel has room for one partial sum (see Rule 67),
+// so the first goroutine to finish doesn't have to wait for the receiver.
+func countChan(inputs []Input, buffered bool) Result {
+	capacity := 0
+	if buffered {
+		capacity = 1
+	}
+	partials := make(chan Result, capacity)
+
+	go func() {
+		var sumA int64
+		for i := 0; i < len(inputs); i++ {
+			sumA += inputs[i].a
+		}
+		partials <- Result{sumA: sumA}
+	}()
+
+	go func() {
+		var sumB int64
+		for i := 0; i < len(inputs); i++ {
+			sumB += inputs[i].b
+		}
+		partials <- Result{sumB: sumB}
+	}()
+
+	result := Result{}
+	for i := 0; i < 2; i++ {
+		partial := <-partials
+		result.sumA += partial.sumA
+		result.sumB += partial.sumB
+	}
+	return result
+}
+
+// CountBenchmark compares performance of count (Result) vs countFast (FastResult), and writes the report to w.
+func CountBenchmark(w io.Writer) {
+	countBenchmark(w, 200000, 50000)
+}
+
+func countBenchmark(w io.Writer, size, iterations int) {
 	// Prepare input data
 	inputs := make([]Input, size)
 	for i := 0; i < size; i++ {
 		inputs[i] = Input{a: int64(i), b: int64(i * 2)}
 	}
 
-	fmt.Printf("Count Benchmark: Result vs FastResult\n")
-	fmt.Printf("Dataset size: %d elements\n", size)
-	fmt.Printf("Iterations: %d\n\n", iterations)
+	fmt.Fprintf(w, "Count Benchmark: Result vs FastResult\n")
+	fmt.Fprintf(w, "Dataset size: %d elements\n", size)
+	fmt.Fprintf(w, "Iterations: %d\n\n", iterations)
 
 	// Benchmark count (Result)
+	roundsResult := newRoundTimer(iterations)
 	start := time.Now()
 	var r Result
 	for i := 0; i < iterations; i++ {
 		r = count(inputs)
+		Consume(r.sumA + r.sumB)
+		roundsResult.tick()
 	}
 	durationResult := time.Since(start)
 
 	// Benchmark countFast (FastResult)
+	roundsFast := newRoundTimer(iterations)
 	start = time.Now()
 	var fr FastResult
 	for i := 0; i < iterations; i++ {
 		fr = countFast(inputs)
+		Consume(fr.sumA + fr.sumB)
+		roundsFast.tick()
 	}
 	durationFast := time.Since(start)
 
-	fmt.Printf("Result (unpadded)\n")
-	fmt.Printf("  sumA: %d, sumB: %d\n", r.sumA, r.sumB)
-	fmt.Printf("  Total time: %v\n", durationResult)
-	fmt.Printf("  Average per operation: %v\n\n", durationResult/time.Duration(iterations))
+	fmt.Fprintf(w, "Result (unpadded)\n")
+	fmt.Fprintf(w, "  sumA: %d, sumB: %d\n", r.sumA, r.sumB)
+	fmt.Fprintf(w, "  Total time: %v\n", durationResult)
+	fmt.Fprintf(w, "  Average per operation: %v\n", durationResult/time.Duration(iterations))
+	fmt.Fprintf(w, "  Std dev across rounds: %v\n\n", roundsResult.stdDev())
 
-	fmt.Printf("FastResult (padded)\n")
-	fmt.Printf("  sumA: %d, sumB: %d\n", fr.sumA, fr.sumB)
-	fmt.Printf("  Total time: %v\n", durationFast)
-	fmt.Printf("  Average per operation: %v\n\n", durationFast/time.Duration(iterations))
+	fmt.Fprintf(w, "FastResult (padded)\n")
+	fmt.Fprintf(w, "  sumA: %d, sumB: %d\n", fr.sumA, fr.sumB)
+	fmt.Fprintf(w, "  Total time: %v\n", durationFast)
+	fmt.Fprintf(w, "  Average per operation: %v\n", durationFast/time.Duration(iterations))
+	fmt.Fprintf(w, "  Std dev across rounds: %v\n\n", roundsFast.stdDev())
 
 	if durationFast < durationResult {
 		ratio := float64(durationResult) / float64(durationFast)
-		fmt.Printf("FastResult is %.2fx faster than Result\n", ratio)
+		fmt.Fprintf(w, "FastResult is %.2fx faster than Result\n", ratio)
 	} else if durationResult < durationFast {
 		ratio := float64(durationFast) / float64(durationResult)
-		fmt.Printf("Result is %.2fx faster than FastResult\n", ratio)
+		fmt.Fprintf(w, "Result is %.2fx faster than FastResult\n", ratio)
 	} else {
-		fmt.Printf("Both versions have similar performance\n")
+		fmt.Fprintf(w, "Both versions have similar performance\n")
 	}
 
 	// Verify results are identical
-	if r.sumA == fr.sumA && r.sumB == fr.sumB {
-		fmt.Printf("Both versions produce the same sums.\n")
+	if EqualSums(r, fr) {
+		fmt.Fprintf(w, "Both versions produce the same sums.\n")
 	} else {
-		fmt.Printf("Mismatch: Result(a=%d,b=%d) vs FastResult(a=%d,b=%d)\n", r.sumA, r.sumB, fr.sumA, fr.sumB)
+		fmt.Fprintf(w, "Mismatch: Result(a=%d,b=%d) vs FastResult(a=%d,b=%d)\n", r.sumA, r.sumB, fr.sumA, fr.sumB)
+	}
+}
+
+// BigInput is large enough (4KB) that copying it is no longer as cheap as copying Input.
+type BigInput struct {
+	a       int64
+	payload [511]int64
+}
+
+func (in *BigInput) sum() int64 {
+	total := in.a
+	for i := 0; i < len(in.payload); i++ {
+		total += in.payload[i]
+	}
+	return total
+}
+
+// sumBigByValue hands each element to its goroutine by value.
+// Arguments of a go statement are copied when the statement is evaluated,
+// so every goroutine start copies the whole 4KB struct into a heap-allocated closure.
+func sumBigByValue(inputs []BigInput) int64 {
+	wg := sync.WaitGroup{}
+	wg.Add(len(inputs))
+
+	partials := make([]int64, len(inputs))
+	for i := 0; i < len(inputs); i++ {
+		go func(i int, in BigInput) {
+			partials[i] = in.sum()
+			wg.Done()
+		}(i, inputs[i])
+	}
+
+	wg.Wait()
+	return sumInt64s(partials)
+}
+
+// sumBigByPointer does the same work, but only a pointer is copied per goroutine.
+// The elements already live in the slice's backing array, so nothing new escapes.
+func sumBigByPointer(inputs []*BigInput) int64 {
+	wg := sync.WaitGroup{}
+	wg.Add(len(inputs))
+
+	partials := make([]int64, len(inputs))
+	for i := 0; i < len(inputs); i++ {
+		go func(i int, in *BigInput) {
+			partials[i] = in.sum()
+			wg.Done()
+		}(i, inputs[i])
+	}
+
+	wg.Wait()
+	return sumInt64s(partials)
+}
+
+func sumInt64s(values []int64) int64 {
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		sum += values[i]
+	}
+	return sum
+}
+
+// The sum functions above index into their slices instead of ranging over them by value.
+// "for _, in := range inputs" copies every element into in. For Input that is two words,
+// which stay in registers, so the two Input loops below run at the same speed.
+// For BigInput the copy is 4KB. The compiler skips it when the loop only reads fields of in,
+// but calling a pointer method such as sum takes the address of in, so the copy has to exist,
+// and sumBigInputByRange is noticeably slower than sumBigInputByIndex, which works in place.
+
+func sumInputByRange(inputs []Input) int64 {
+	var sum int64
+	for _, in := range inputs {
+		sum += in.a + in.b
+	}
+	return sum
+}
+
+func sumInputByIndex(inputs []Input) int64 {
+	var sum int64
+	for i := 0; i < len(inputs); i++ {
+		sum += inputs[i].a + inputs[i].b
+	}
+	return sum
+}
+
+func sumBigInputByRange(inputs []BigInput) int64 {
+	var sum int64
+	for _, in := range inputs {
+		sum += in.sum()
+	}
+	return sum
+}
+
+func sumBigInputByIndex(inputs []BigInput) int64 {
+	var sum int64
+	for i := 0; i < len(inputs); i++ {
+		sum += inputs[i].sum()
+	}
+	return sum
+}
+
+// SimpleBenchmark and CountBenchmark read the clock around the whole loop, and roundTimer once per round.
+// Timing every iteration instead would measure the clock as much as the work: a time.Now call
+// costs tens of nanoseconds or more (see BenchmarkTimeNow), as much as many small operations,
+// and it sits inside the measured region.
+// Time coarse-grained units of work, and divide by their count, like testing.B does with b.N.
+
+// sumTimedOnce sums values and measures the whole loop.
+func sumTimedOnce(values []int64) (int64, time.Duration) {
+	start := time.Now()
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		sum += values[i]
+	}
+	return sum, time.Since(start)
+}
+
+// sumTimedEach sums values and measures every addition separately.
+// The returned duration is the total of the measurements, which is mostly the clock reads.
+func sumTimedEach(values []int64) (int64, time.Duration) {
+	var sum int64
+	var total time.Duration
+	for i := 0; i < len(values); i++ {
+		start := time.Now()
+		sum += values[i]
+		total += time.Since(start)
+	}
+	return sum, total
+}
+
+// arrayLen is the length of the array passed by sumArray, 32KB of int64s.
+const arrayLen = 4096
+
+// sumArray takes the array by value: an array is its elements, so every call copies all 32KB of them.
+// A slice is only a pointer, a length and a capacity, so sumSlice copies 24 bytes
+// and reads the elements from the backing array it shares with the caller, like Bar's slices in sumBar.
+// Both are kept out of line, as inlining sumArray would let the compiler skip the copy.
+//
+//go:noinline
+func sumArray(values [arrayLen]int64) int64 {
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		sum += values[i]
+	}
+	return sum
+}
+
+//go:noinline
+func sumSlice(values []int64) int64 {
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		sum += values[i]
+	}
+	return sum
+}
+
+// addOne is the tiny piece of work that BenchmarkFunctionCall and BenchmarkChannelRoundTrip hand over.
+// It is kept out of line so the function call is really paid for.
+//
+//go:noinline
+func addOne(x int64) int64 {
+	return x + 1
+}
+
+// sumParsed is sumInt64s for values that arrive as text, e.g. read from a file or a request.
+// strconv.ParseInt works on the string in place and doesn't allocate when it succeeds,
+// so the difference with sumInt64s is the parsing itself.
+func sumParsed(values []string) (int64, error) {
+	var sum int64
+	for i := 0; i < len(values); i++ {
+		v, err := strconv.ParseInt(values[i], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return sum, nil
+}
+
+func newBigInputs(size int) ([]BigInput, []*BigInput) {
+	values := make([]BigInput, size)
+	pointers := make([]*BigInput, size)
+	for i := 0; i < size; i++ {
+		values[i].a = int64(i)
+		for j := 0; j < len(values[i].payload); j++ {
+			values[i].payload[j] = int64(j)
+		}
+		pointers[i] = &values[i]
 	}
+	return values, pointers
 }
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSumBigByValueMatchesByPointer(t *testing.T) {
+	values, pointers := newBigInputs(100)
+
+	var want int64
+	for i := 0; i < len(values); i++ {
+		want += values[i].sum()
+	}
+
+	if got := sumBigByValue(values); got != want {
+		t.Fatalf("sumBigByValue() = %d, want %d", got, want)
+	}
+	if got := sumBigByPointer(pointers); got != want {
+		t.Fatalf("sumBigByPointer() = %d, want %d", got, want)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	for _, v := range []int64{1, -42, 1 << 40} {
+		Consume(v)
+		if got := atomic.LoadInt64(&Sink); got != v {
+			t.Fatalf("Sink = %d after Consume(%d)", got, v)
+		}
+	}
+}
+
+func TestSumComputeVariantsAgree(t *testing.T) {
+	fooSlice, bar := newComputeData(1000)
+
+	var want int64
+	for i := 0; i < 1000; i++ {
+		want += mix(int64(i))
+	}
+
+	if got := sumFooCompute(fooSlice); got != want {
+		t.Fatalf("sumFooCompute() = %d, want %d", got, want)
+	}
+	if got := sumBarCompute(bar); got != want {
+		t.Fatalf("sumBarCompute() = %d, want %d", got, want)
+	}
+}
+
+func TestResultsEqual(t *testing.T) {
+	for _, testcase := range []struct {
+		name  string
+		a, b  Result
+		wants bool
+	}{
+		{name: "Equal", a: Result{sumA: 1, sumB: 2}, b: Result{sumA: 1, sumB: 2}, wants: true},
+		{name: "DifferentA", a: Result{sumA: 1, sumB: 2}, b: Result{sumA: 3, sumB: 2}, wants: false},
+		{name: "DifferentB", a: Result{sumA: 1, sumB: 2}, b: Result{sumA: 1, sumB: 3}, wants: false},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			if got := ResultsEqual(testcase.a, testcase.b); got != testcase.wants {
+				t.Fatalf("ResultsEqual(%+v, %+v) = %v, want %v", testcase.a, testcase.b, got, testcase.wants)
+			}
+
+			fastA := FastResult{sumA: testcase.a.sumA, sumB: testcase.a.sumB}
+			fastB := FastResult{sumA: testcase.b.sumA, sumB: testcase.b.sumB}
+			if got := FastResultsEqual(fastA, fastB); got != testcase.wants {
+				t.Fatalf("FastResultsEqual(%+v, %+v) = %v, want %v", fastA, fastB, got, testcase.wants)
+			}
+			if got := EqualSums(testcase.a, fastB); got != testcase.wants {
+				t.Fatalf("EqualSums(%+v, %+v) = %v, want %v", testcase.a, fastB, got, testcase.wants)
+			}
+		})
+	}
+}
+
+func TestSumGenericMatchesSumIface(t *testing.T) {
+	values := []int64{1, 1000, -5, 1 << 40}
+	want := int64(1 + 1000 - 5 + 1<<40)
+
+	if got := SumGeneric(values); got != want {
+		t.Fatalf("SumGeneric() = %d, want %d", got, want)
+	}
+	if got := SumIface(boxInt64s(values)); got != want {
+		t.Fatalf("SumIface() = %d, want %d", got, want)
+	}
+	if got := SumGeneric([]float64{0.5, 0.25}); got != 0.75 {
+		t.Fatalf("SumGeneric() = %v, want 0.75", got)
+	}
+}
+
+func TestSumFooPtrSliceMatchesSumFoo(t *testing.T) {
+	pointers := newFooPtrs(1000)
+	values := make([]Foo, len(pointers))
+	for i, foo := range pointers {
+		values[i] = *foo
+	}
+
+	if got, want := sumFooPtrSlice(pointers), sumFoo(values); got != want {
+		t.Fatalf("sumFooPtrSlice() = %d, sumFoo() = %d; want equal", got, want)
+	}
+}
+
+func TestCountVariantsAgree(t *testing.T) {
+	inputs := newInputs(10000)
+
+	r := count(inputs)
+	fr := countFast(inputs)
+	sumA, sumB := countSeparate(inputs)
+
+	if !EqualSums(r, fr) {
+		t.Fatalf("count() = %+v, countFast() = %+v; want equal sums", r, fr)
+	}
+	if *sumA != r.sumA || *sumB != r.sumB {
+		t.Fatalf("countSeparate() = %d, %d; want %d, %d", *sumA, *sumB, r.sumA, r.sumB)
+	}
+	if got, ok := countTimeout(inputs, time.Second); !ok || got != r {
+		t.Fatalf("countTimeout() = %+v, %v; want %+v, true", got, ok, r)
+	}
+	if got, ok := countFastTimeout(inputs, time.Second); !ok || !EqualSums(r, got) {
+		t.Fatalf("countFastTimeout() = %+v, %v; want the sums of %+v, true", got, ok, r)
+	}
+	if got, err := countCtx(context.Background(), inputs); err != nil || got != r {
+		t.Fatalf("countCtx() = %+v, %v; want %+v, nil", got, err, r)
+	}
+	for _, buffered := range []bool{false, true} {
+		if got := countChan(inputs, buffered); got != r {
+			t.Fatalf("countChan(buffered=%v) = %+v, want %+v", buffered, got, r)
+		}
+	}
+}
+
+// cancelAfterChecks is a context that reports itself cancelled once Err was called limit times,
+// so a test can cancel a loop that checks its context at a known point, whatever the speed of the machine.
+type cancelAfterChecks struct {
+	context.Context
+	checks atomic.Int64
+	limit  int64
+}
+
+func (c *cancelAfterChecks) Err() error {
+	if c.checks.Add(1) > c.limit {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestCountCtxCancelled(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	inputs := newInputs(1 << 20)
+
+	// Each goroutine checks 256 times over the whole input, so the sum is cancelled halfway through.
+	ctx := &cancelAfterChecks{Context: context.Background(), limit: 256}
+	result, err := countCtx(ctx, inputs)
+
+	if !errors.Is(err, context.Canceled) || result != (Result{}) {
+		t.Fatalf("countCtx() = %+v, %v; want %+v, %v", result, err, Result{}, context.Canceled)
+	}
+	// Both goroutines stop at their next check, instead of finishing their half.
+	if checks := ctx.checks.Load(); checks > ctx.limit+2 {
+		t.Fatalf("context checked %d times, want at most %d", checks, ctx.limit+2)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestCountTimeoutExpired(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	inputs := newInputs(1 << 20)
+
+	// The deadline has passed by the time the goroutines first check it.
+	if result, ok := countTimeout(inputs, time.Nanosecond); ok || result != (Result{}) {
+		t.Fatalf("countTimeout() = %+v, %v; want %+v, false", result, ok, Result{})
+	}
+	if result, ok := countFastTimeout(inputs, time.Nanosecond); ok || result != (FastResult{}) {
+		t.Fatalf("countFastTimeout() = %+v, %v; want %+v, false", result, ok, FastResult{})
+	}
+	// Both stopped their goroutines before returning.
+	waitForGoroutines(t, baseline)
+}
+
+func TestCountCtxAlreadyCancelled(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	inputs := newInputs(1 << 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if _, err := countCtx(ctx, inputs); !errors.Is(err, context.Canceled) {
+		t.Fatalf("countCtx() = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("countCtx() returned after %v with a cancelled context", elapsed)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestSumParsed(t *testing.T) {
+	text, values := newNumbers(1000)
+
+	got, err := sumParsed(text)
+	if err != nil {
+		t.Fatalf("sumParsed() error = %v", err)
+	}
+	if want := sumInt64s(values); got != want {
+		t.Fatalf("sumParsed() = %d, want %d", got, want)
+	}
+
+	if _, err := sumParsed([]string{"1", "two"}); !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("sumParsed() error = %v, want %v", err, strconv.ErrSyntax)
+	}
+}
+
+func TestBenchmarkReports(t *testing.T) {
+	for _, testcase := range []struct {
+		name  string
+		run   func(w io.Writer)
+		lines []string
+	}{
+		{
+			name: "SimpleBenchmark",
+			run:  func(w io.Writer) { simpleBenchmark(w, 100, 3) },
+			lines: []string{
+				"Dataset size: 100 elements",
+				"sumFoo Results:",
+				"sumBar Results:",
+				"  Result: 4950",
+				"  Total time: ",
+				"Both functions produce the same result: 4950",
+			},
+		},
+		{
+			name: "CountBenchmark",
+			run:  func(w io.Writer) { countBenchmark(w, 100, 3) },
+			lines: []string{
+				"Dataset size: 100 elements",
+				"Result (unpadded)",
+				"FastResult (padded)",
+				"  sumA: 4950, sumB: 9900",
+				"  Total time: ",
+				"Both versions produce the same sums.",
+			},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			var out bytes.Buffer
+			testcase.run(&out)
+
+			for _, line := range testcase.lines {
+				if !strings.Contains(out.String(), line) {
+					t.Fatalf("output is missing %q:\n%s", line, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSumByRangeMatchesByIndex(t *testing.T) {
+	inputs := newInputs(1000)
+	want := sumInputs(inputs)
+	if got := sumInputByRange(inputs); got != want.sumA+want.sumB {
+		t.Fatalf("sumInputByRange() = %d, want %d", got, want.sumA+want.sumB)
+	}
+	if got := sumInputByIndex(inputs); got != want.sumA+want.sumB {
+		t.Fatalf("sumInputByIndex() = %d, want %d", got, want.sumA+want.sumB)
+	}
+
+	bigInputs, _ := newBigInputs(100)
+	var bigWant int64
+	for i := 0; i < len(bigInputs); i++ {
+		bigWant += bigInputs[i].sum()
+	}
+	if got := sumBigInputByRange(bigInputs); got != bigWant {
+		t.Fatalf("sumBigInputByRange() = %d, want %d", got, bigWant)
+	}
+	if got := sumBigInputByIndex(bigInputs); got != bigWant {
+		t.Fatalf("sumBigInputByIndex() = %d, want %d", got, bigWant)
+	}
+}
+
+func TestSumTimed(t *testing.T) {
+	values := []int64{1, 2, 3, 4}
+
+	if sum, d := sumTimedOnce(values); sum != 10 || d < 0 {
+		t.Fatalf("sumTimedOnce() = %d, %v; want 10 and a non-negative duration", sum, d)
+	}
+	if sum, d := sumTimedEach(values); sum != 10 || d < 0 {
+		t.Fatalf("sumTimedEach() = %d, %v; want 10 and a non-negative duration", sum, d)
+	}
+}
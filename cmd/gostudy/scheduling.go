@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BusyLoop keeps a CPU busy with real arithmetic for approximately d.
+// Unlike time.Sleep, which parks the goroutine and frees its P for other goroutines,
+// a spinning goroutine holds on to its P, so it is useful to study how GOMAXPROCS limits parallelism.
+func BusyLoop(d time.Duration) {
+	deadline := time.Now().Add(d)
+
+	x := int64(1)
+	for time.Now().Before(deadline) {
+		// Reading the clock costs more than a handful of multiplies, so only check it now and then.
+		for i := 0; i < 1000; i++ {
+			x = x*6364136223846793005 + 1442695040888963407
+		}
+	}
+	Consume(x)
+}
+
+// WithMaxProcs runs fn with GOMAXPROCS set to n, and restores the previous value afterwards,
+// even if fn panics. GOMAXPROCS is global, so experiments running concurrently affect each other.
+func WithMaxProcs(n int, fn func()) {
+	previous := runtime.GOMAXPROCS(n)
+	defer runtime.GOMAXPROCS(previous)
+	fn()
+}
+
+// Paths of the CPU quota files of the current cgroup, for cgroup v2 and v1.
+const (
+	cgroupV2CPUMax       = "/sys/fs/cgroup/cpu.max"
+	cgroupV1CFSQuotaUs   = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cgroupV1CFSPeriodUs  = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+	cgroupUnlimitedQuota = "max"
+)
+
+// readFileFunc reads a whole file, like os.ReadFile. Tests replace it with fabricated cgroup files.
+type readFileFunc func(path string) ([]byte, error)
+
+// SetMaxProcsFromCgroup sets GOMAXPROCS to the CPU quota of the container, and returns the value used.
+// Before Go 1.25, the runtime only looked at the CPUs of the host, so a container limited to 2 CPUs
+// on a 64-core host would run 64 threads that get throttled by the CFS quota.
+// Since Go 1.25 the runtime does this on its own, and keeps GOMAXPROCS up to date when the quota changes,
+// until GOMAXPROCS is set explicitly: so this must only be called when runtimeFollowsCgroupQuota reports false.
+//
+// The quota is capped at runtime.NumCPU(), as a quota can exceed the CPUs of the host.
+// GOMAXPROCS is left alone when there is no quota, or when the GOMAXPROCS environment variable is set.
+func SetMaxProcsFromCgroup() (int, error) {
+	return setMaxProcsFromCgroup(os.ReadFile, os.LookupEnv, runtime.NumCPU())
+}
+
+func setMaxProcsFromCgroup(readFile readFileFunc, lookupEnv func(string) (string, bool), numCPU int) (int, error) {
+	if _, ok := lookupEnv("GOMAXPROCS"); ok {
+		// The user chose the value, and the runtime already applied it.
+		return runtime.GOMAXPROCS(0), nil
+	}
+	procs, limited, err := cgroupMaxProcs(readFile)
+	if err != nil {
+		return runtime.GOMAXPROCS(0), err
+	}
+	if !limited {
+		return runtime.GOMAXPROCS(0), nil
+	}
+	procs = min(procs, numCPU)
+	runtime.GOMAXPROCS(procs)
+	return procs, nil
+}
+
+// runtimeFollowsCgroupQuota reports whether the runtime sets GOMAXPROCS from the cgroup CPU quota on its own.
+// That takes a Go 1.25 toolchain, and the containermaxprocs GODEBUG setting, whose default follows
+// the go version of go.mod rather than the toolchain: a module still on go 1.24 is built with containermaxprocs=0.
+func runtimeFollowsCgroupQuota() bool {
+	defaultGODEBUG := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "DefaultGODEBUG" {
+				defaultGODEBUG = setting.Value
+			}
+		}
+	}
+	return followsCgroupQuota(toolchainFollowsCgroupQuota, defaultGODEBUG, os.Getenv("GODEBUG"))
+}
+
+// followsCgroupQuota applies the defaults of the build, and then the GODEBUG environment variable, which overrides them.
+func followsCgroupQuota(toolchain bool, defaultGODEBUG, envGODEBUG string) bool {
+	if !toolchain {
+		return false
+	}
+	enabled := true
+	for _, settings := range []string{defaultGODEBUG, envGODEBUG} {
+		for _, setting := range strings.Split(settings, ",") {
+			switch strings.TrimSpace(setting) {
+			case "containermaxprocs=0":
+				enabled = false
+			case "containermaxprocs=1":
+				enabled = true
+			}
+		}
+	}
+	return enabled
+}
+
+// cgroupMaxProcs returns the number of CPUs allowed by the cgroup CPU quota, rounded down to at least 1.
+// limited is false when there is no quota, or no cgroup files at all.
+func cgroupMaxProcs(readFile readFileFunc) (procs int, limited bool, err error) {
+	data, err := readFile(cgroupV2CPUMax)
+	if err == nil {
+		// cgroup v2: "<quota> <period>", where quota may be "max".
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 {
+			return 0, false, fmt.Errorf("malformed %s: %q", cgroupV2CPUMax, data)
+		}
+		if fields[0] == cgroupUnlimitedQuota {
+			return 0, false, nil
+		}
+		return quotaToProcs(fields[0], fields[1])
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return 0, false, err
+	}
+
+	// cgroup v1: the quota and the period are in separate files, and a quota of -1 means no limit.
+	quota, err := readFile(cgroupV1CFSQuotaUs)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if strings.TrimSpace(string(quota)) == "-1" {
+		return 0, false, nil
+	}
+	period, err := readFile(cgroupV1CFSPeriodUs)
+	if err != nil {
+		return 0, false, err
+	}
+	return quotaToProcs(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func quotaToProcs(quota, period string) (int, bool, error) {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("malformed cgroup CPU quota %q: %w", quota, err)
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("malformed cgroup CPU period %q: %w", period, err)
+	}
+	if q <= 0 || p <= 0 {
+		return 0, false, fmt.Errorf("invalid cgroup CPU quota %d/%d", q, p)
+	}
+
+	procs := int(q / p)
+	if procs < 1 {
+		procs = 1
+	}
+	return procs, true, nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBusyLoop(t *testing.T) {
+	const d = 20 * time.Millisecond
+
+	start := time.Now()
+	BusyLoop(d)
+
+	if elapsed := time.Since(start); elapsed < d {
+		t.Fatalf("BusyLoop(%v) returned after %v", d, elapsed)
+	}
+}
+
+// BenchmarkBusyLoopGOMAXPROCS runs 8 spinning goroutines of 1ms each under different GOMAXPROCS.
+// With GOMAXPROCS=1 the goroutines take turns, and the wall time is about 8ms.
+// Up to the number of CPUs, they run in parallel and the wall time drops toward 1ms.
+// Beyond that, the OS time-slices the threads: every BusyLoop still ends at its wall-clock deadline,
+// but each one only had a fraction of a CPU, so a higher GOMAXPROCS only adds contention.
+// This is why GOMAXPROCS should follow the CPU quota of a container, not the CPUs of the host.
+func BenchmarkBusyLoopGOMAXPROCS(b *testing.B) {
+	const goroutines = 8
+	const d = time.Millisecond
+
+	for _, procs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("GOMAXPROCS=%d", procs), func(b *testing.B) {
+			WithMaxProcs(procs, func() {
+				for i := 0; i < b.N; i++ {
+					wg := sync.WaitGroup{}
+					wg.Add(goroutines)
+					for j := 0; j < goroutines; j++ {
+						go func() {
+							defer wg.Done()
+							BusyLoop(d)
+						}()
+					}
+					wg.Wait()
+				}
+			})
+			b.ReportMetric(float64(b.Elapsed().Milliseconds())/float64(b.N), "wall-ms/op")
+		})
+	}
+}
+
+func TestWithMaxProcs(t *testing.T) {
+	previous := runtime.GOMAXPROCS(0)
+	n := previous + 1
+
+	WithMaxProcs(n, func() {
+		if got := runtime.GOMAXPROCS(0); got != n {
+			t.Fatalf("GOMAXPROCS inside fn = %d, want %d", got, n)
+		}
+	})
+	if got := runtime.GOMAXPROCS(0); got != previous {
+		t.Fatalf("GOMAXPROCS after fn = %d, want %d", got, previous)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("the panic of fn was not propagated")
+			}
+		}()
+		WithMaxProcs(n, func() { panic("experiment failed") })
+	}()
+	if got := runtime.GOMAXPROCS(0); got != previous {
+		t.Fatalf("GOMAXPROCS after a panicking fn = %d, want %d", got, previous)
+	}
+}
+
+// fakeCgroup serves fabricated cgroup files, and reports every other path as missing.
+func fakeCgroup(files map[string]string) readFileFunc {
+	return func(path string) ([]byte, error) {
+		data, ok := files[path]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return []byte(data), nil
+	}
+}
+
+func TestCgroupMaxProcs(t *testing.T) {
+	for _, testcase := range []struct {
+		name        string
+		files       map[string]string
+		wantProcs   int
+		wantLimited bool
+		wantErr     bool
+	}{
+		{
+			name:        "V2Limited",
+			files:       map[string]string{cgroupV2CPUMax: "250000 100000\n"},
+			wantProcs:   2,
+			wantLimited: true,
+		},
+		{
+			name:        "V2LessThanOneCPU",
+			files:       map[string]string{cgroupV2CPUMax: "50000 100000\n"},
+			wantProcs:   1,
+			wantLimited: true,
+		},
+		{
+			name:  "V2Unlimited",
+			files: map[string]string{cgroupV2CPUMax: "max 100000\n"},
+		},
+		{
+			name:    "V2Malformed",
+			files:   map[string]string{cgroupV2CPUMax: "garbage\n"},
+			wantErr: true,
+		},
+		{
+			name:    "V2MalformedQuota",
+			files:   map[string]string{cgroupV2CPUMax: "lots 100000\n"},
+			wantErr: true,
+		},
+		{
+			name: "V1Limited",
+			files: map[string]string{
+				cgroupV1CFSQuotaUs:  "400000\n",
+				cgroupV1CFSPeriodUs: "100000\n",
+			},
+			wantProcs:   4,
+			wantLimited: true,
+		},
+		{
+			name: "V1Unlimited",
+			files: map[string]string{
+				cgroupV1CFSQuotaUs:  "-1\n",
+				cgroupV1CFSPeriodUs: "100000\n",
+			},
+		},
+		{
+			name: "V1MalformedPeriod",
+			files: map[string]string{
+				cgroupV1CFSQuotaUs:  "400000\n",
+				cgroupV1CFSPeriodUs: "\n",
+			},
+			wantErr: true,
+		},
+		{
+			name:  "NoCgroup",
+			files: map[string]string{},
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			procs, limited, err := cgroupMaxProcs(fakeCgroup(testcase.files))
+
+			if (err != nil) != testcase.wantErr {
+				t.Fatalf("cgroupMaxProcs() error = %v, want error: %v", err, testcase.wantErr)
+			}
+			if procs != testcase.wantProcs || limited != testcase.wantLimited {
+				t.Fatalf("cgroupMaxProcs() = %d, %v; want %d, %v", procs, limited, testcase.wantProcs, testcase.wantLimited)
+			}
+		})
+	}
+}
+
+func TestSetMaxProcsFromCgroup(t *testing.T) {
+	previous := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(previous)
+
+	noEnv := func(string) (string, bool) { return "", false }
+
+	procs, err := setMaxProcsFromCgroup(fakeCgroup(map[string]string{cgroupV2CPUMax: "300000 100000"}), noEnv, 8)
+	if err != nil || procs != 3 || runtime.GOMAXPROCS(0) != 3 {
+		t.Fatalf("limited: got %d, %v and GOMAXPROCS %d; want 3, nil and 3", procs, err, runtime.GOMAXPROCS(0))
+	}
+
+	procs, err = setMaxProcsFromCgroup(fakeCgroup(map[string]string{cgroupV2CPUMax: "1600000 100000"}), noEnv, 4)
+	if err != nil || procs != 4 || runtime.GOMAXPROCS(0) != 4 {
+		t.Fatalf("quota above the CPUs: got %d, %v and GOMAXPROCS %d; want 4, nil and 4", procs, err, runtime.GOMAXPROCS(0))
+	}
+
+	runtime.GOMAXPROCS(2)
+	procs, err = setMaxProcsFromCgroup(fakeCgroup(map[string]string{}), noEnv, 8)
+	if err != nil || procs != 2 || runtime.GOMAXPROCS(0) != 2 {
+		t.Fatalf("unlimited: got %d, %v and GOMAXPROCS %d; want 2, nil and 2 unchanged", procs, err, runtime.GOMAXPROCS(0))
+	}
+
+	env := func(key string) (string, bool) { return "2", key == "GOMAXPROCS" }
+	procs, err = setMaxProcsFromCgroup(fakeCgroup(map[string]string{cgroupV2CPUMax: "300000 100000"}), env, 8)
+	if err != nil || procs != 2 || runtime.GOMAXPROCS(0) != 2 {
+		t.Fatalf("GOMAXPROCS set in the environment: got %d, %v and GOMAXPROCS %d; want 2, nil and 2 unchanged", procs, err, runtime.GOMAXPROCS(0))
+	}
+
+	procs, err = setMaxProcsFromCgroup(fakeCgroup(map[string]string{cgroupV2CPUMax: "oops"}), noEnv, 8)
+	if err == nil || procs != 2 || runtime.GOMAXPROCS(0) != 2 {
+		t.Fatalf("malformed: got %d, %v and GOMAXPROCS %d; want 2, an error and 2 unchanged", procs, err, runtime.GOMAXPROCS(0))
+	}
+}
+
+func TestFollowsCgroupQuota(t *testing.T) {
+	for _, testcase := range []struct {
+		name           string
+		toolchain      bool
+		defaultGODEBUG string
+		envGODEBUG     string
+		want           bool
+	}{
+		{name: "OldToolchain", toolchain: false, want: false},
+		{name: "OldToolchainEnabledByEnv", toolchain: false, envGODEBUG: "containermaxprocs=1", want: false},
+		{name: "NewToolchain", toolchain: true, want: true},
+		{name: "OldGoMod", toolchain: true, defaultGODEBUG: "containermaxprocs=0,updatemaxprocs=0", want: false},
+		{name: "OldGoModEnabledByEnv", toolchain: true, defaultGODEBUG: "containermaxprocs=0", envGODEBUG: "containermaxprocs=1", want: true},
+		{name: "DisabledByEnv", toolchain: true, envGODEBUG: "gctrace=1,containermaxprocs=0", want: false},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			if got := followsCgroupQuota(testcase.toolchain, testcase.defaultGODEBUG, testcase.envGODEBUG); got != testcase.want {
+				t.Fatalf("followsCgroupQuota() = %v, want %v", got, testcase.want)
+			}
+		})
+	}
+}
+
+// TestRuntimeFollowsCgroupQuotaMatchesBuild checks the setting this very binary was built with:
+// as long as go.mod says go 1.24, the runtime ignores the cgroup quota, and main has to apply it.
+func TestRuntimeFollowsCgroupQuotaMatchesBuild(t *testing.T) {
+	if strings.Contains(os.Getenv("GODEBUG"), "containermaxprocs") {
+		t.Skip("GODEBUG overrides containermaxprocs")
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		t.Skip("no build information")
+	}
+	disabled := false
+	for _, setting := range info.Settings {
+		if setting.Key == "DefaultGODEBUG" && slices.Contains(strings.Split(setting.Value, ","), "containermaxprocs=0") {
+			disabled = true
+		}
+	}
+	if disabled && runtimeFollowsCgroupQuota() {
+		t.Fatal("runtimeFollowsCgroupQuota() = true, but the binary was built with containermaxprocs=0")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errScopeDone is the cause of the cancellation of a scope whose fn returned without an error.
+var errScopeDone = errors.New("scope done")
+
+// Scope tracks the goroutines started with Go, so that WithScope can wait for all of them.
+// It is what golang.org/x/sync/errgroup does, with the lifetime bound to a function call.
+type Scope struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// WithScope calls fn with a new Scope, and doesn't return before every goroutine fn started with s.Go has finished.
+// Goroutines therefore can't outlive the call that started them, so they can't leak (see Rule 62).
+// The context of the children is cancelled when one of them fails, or when fn returns.
+// WithScope returns the first error of fn or of a child. A child returning ctx.Err() because fn returned
+// isn't a failure, so it is ignored.
+func WithScope(ctx context.Context, fn func(s *Scope) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	s := &Scope{ctx: ctx, cancel: cancel}
+
+	s.fail(fn(s))
+	cancel(errScopeDone)
+	s.wg.Wait()
+	return s.err
+}
+
+// Go starts fn in a new goroutine, with a context that is cancelled when the scope ends.
+// It must only be called before WithScope returns, i.e. from fn or from another child.
+func (s *Scope) Go(fn func(ctx context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.fail(fn(s.ctx))
+	}()
+}
+
+func (s *Scope) fail(err error) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) && context.Cause(s.ctx) == errScopeDone {
+		return
+	}
+	s.errOnce.Do(func() {
+		s.err = err
+		s.cancel(err)
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithScope(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	var done atomic.Int64
+
+	err := WithScope(context.Background(), func(s *Scope) error {
+		for range 10 {
+			s.Go(func(context.Context) error {
+				time.Sleep(time.Millisecond)
+				done.Add(1)
+				return nil
+			})
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("WithScope() = %v, want nil", err)
+	}
+	// Every child has finished by the time WithScope returns, without waiting for anything else.
+	if done.Load() != 10 {
+		t.Fatalf("%d children finished when WithScope returned, want 10", done.Load())
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestWithScopeChildError(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	errChild := errors.New("child failed")
+
+	err := WithScope(context.Background(), func(s *Scope) error {
+		s.Go(func(context.Context) error {
+			return errChild
+		})
+		// The other children would block forever if the failure didn't cancel them.
+		for range 5 {
+			s.Go(func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errChild) {
+		t.Fatalf("WithScope() = %v, want %v", err, errChild)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestWithScopeFnError(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	errFn := errors.New("fn failed")
+	var cancelled atomic.Bool
+
+	err := WithScope(context.Background(), func(s *Scope) error {
+		s.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			cancelled.Store(true)
+			return nil
+		})
+		return errFn
+	})
+
+	if !errors.Is(err, errFn) {
+		t.Fatalf("WithScope() = %v, want %v", err, errFn)
+	}
+	if !cancelled.Load() {
+		t.Fatal("child was not cancelled before WithScope returned")
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestWithScopeParentCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WithScope(ctx, func(s *Scope) error {
+		s.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithScope() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithScopeIgnoresOwnCancellation(t *testing.T) {
+	err := WithScope(context.Background(), func(s *Scope) error {
+		s.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("WithScope() = %v, want nil", err)
+	}
+}
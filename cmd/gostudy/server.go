@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrServerStopped is returned by Server.Call once the server has been stopped.
+var ErrServerStopped = errors.New("server: stopped")
+
+// Request carries a payload to a Server, along with the channel its response goes back on.
+type Request struct {
+	payload int
+	reply   chan int
+}
+
+// Server answers requests on its own goroutine, which owns all the state it needs:
+// the request/reply pattern shares memory by communicating.
+//
+// Every request brings its own reply channel, buffered for the one response,
+// so the server never blocks on a client that stopped waiting, and can't deadlock with it.
+type Server struct {
+	handle   func(int) int
+	requests chan Request
+	stop     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+// NewServer starts a server that answers every request with handle(payload).
+// Stop must be called to stop its goroutine (see Rule 62).
+func NewServer(handle func(int) int) *Server {
+	s := &Server{
+		handle:   handle,
+		requests: make(chan Request),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *Server) serve() {
+	defer close(s.stopped)
+	for {
+		select {
+		case req := <-s.requests:
+			req.reply <- s.handle(req.payload)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Call sends payload to the server and waits for the response, until ctx is cancelled.
+// If ctx is cancelled after the request was sent, the response is dropped into the reply buffer
+// and collected with it.
+func (s *Server) Call(ctx context.Context, payload int) (int, error) {
+	req := Request{payload: payload, reply: make(chan int, 1)}
+
+	select {
+	case s.requests <- req:
+	case <-s.stopped:
+		return 0, ErrServerStopped
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	select {
+	case response := <-req.reply:
+		return response, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Stop stops the server and waits for its goroutine to exit. It is safe to call more than once.
+// A request that is being handled is completed first.
+func (s *Server) Stop() {
+	s.once.Do(func() { close(s.stop) })
+	<-s.stopped
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerCall(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	server := NewServer(func(v int) int { return v * v })
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := server.Call(context.Background(), i); err != nil || got != i*i {
+				t.Errorf("Call(%d) = %d, %v; want %d, nil", i, got, err, i*i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	server.Stop()
+	server.Stop()
+	if _, err := server.Call(context.Background(), 1); !errors.Is(err, ErrServerStopped) {
+		t.Fatalf("Call() after Stop = %v, want %v", err, ErrServerStopped)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestServerCallCancelledWhileWaiting(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	release := make(chan struct{})
+	server := NewServer(func(v int) int {
+		<-release
+		return v
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := server.Call(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The server delivers the abandoned response into the buffered reply channel, and keeps serving.
+	close(release)
+	if got, err := server.Call(context.Background(), 2); err != nil || got != 2 {
+		t.Fatalf("Call() after an abandoned call = %d, %v; want 2, nil", got, err)
+	}
+
+	server.Stop()
+	waitForGoroutines(t, baseline)
+}
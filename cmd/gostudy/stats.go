@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// RunningStats computes the mean and variance of a stream of samples without storing them,
+// using Welford's online algorithm. The naive sum/sum-of-squares approach subtracts two large,
+// nearly equal numbers, and loses most of its precision when the variance is small compared to the mean.
+type RunningStats struct {
+	count int
+	mean  float64
+	m2    float64 // sum of squared distances from the mean
+}
+
+func (s *RunningStats) Add(x float64) {
+	s.count++
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+}
+
+func (s *RunningStats) Count() int {
+	return s.count
+}
+
+func (s *RunningStats) Mean() float64 {
+	return s.mean
+}
+
+// Variance returns the sample variance, or 0 when there are fewer than two samples.
+func (s *RunningStats) Variance() float64 {
+	if s.count < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.count-1)
+}
+
+func (s *RunningStats) StdDev() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// benchmarkRounds is the number of rounds the iterations of a benchmark are split into.
+const benchmarkRounds = 20
+
+// roundTimer splits a timed loop into rounds, and adds the average time per operation
+// of every round to stats. It only reads the clock once per round, not once per iteration.
+type roundTimer struct {
+	stats RunningStats
+	size  int
+	n     int
+	start time.Time
+}
+
+func newRoundTimer(iterations int) *roundTimer {
+	size := iterations / benchmarkRounds
+	if size < 1 {
+		size = 1
+	}
+	return &roundTimer{size: size, start: time.Now()}
+}
+
+// tick must be called once per iteration.
+func (r *roundTimer) tick() {
+	r.n++
+	if r.n < r.size {
+		return
+	}
+
+	now := time.Now()
+	r.stats.Add(float64(now.Sub(r.start)) / float64(r.size))
+	r.start = now
+	r.n = 0
+}
+
+func (r *roundTimer) stdDev() time.Duration {
+	return time.Duration(r.stats.StdDev())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRunningStats(t *testing.T) {
+	samples := []float64{2, 4, 4, 4, 5, 5, 7, 9, 1e9 + 1, 1e9 + 3}
+
+	var stats RunningStats
+	for _, x := range samples {
+		stats.Add(x)
+	}
+
+	// Naive two-pass computation as reference.
+	mean := 0.
+	for _, x := range samples {
+		mean += x
+	}
+	mean /= float64(len(samples))
+	variance := 0.
+	for _, x := range samples {
+		variance += (x - mean) * (x - mean)
+	}
+	variance /= float64(len(samples) - 1)
+
+	const tolerance = 1e-9
+	if stats.Count() != len(samples) {
+		t.Fatalf("Count() = %d, want %d", stats.Count(), len(samples))
+	}
+	if math.Abs(stats.Mean()-mean)/mean > tolerance {
+		t.Fatalf("Mean() = %v, want %v", stats.Mean(), mean)
+	}
+	if math.Abs(stats.Variance()-variance)/variance > tolerance {
+		t.Fatalf("Variance() = %v, want %v", stats.Variance(), variance)
+	}
+	if math.Abs(stats.StdDev()-math.Sqrt(variance))/math.Sqrt(variance) > tolerance {
+		t.Fatalf("StdDev() = %v, want %v", stats.StdDev(), math.Sqrt(variance))
+	}
+}
+
+func TestRunningStatsFewSamples(t *testing.T) {
+	var stats RunningStats
+	if stats.Mean() != 0 || stats.Variance() != 0 {
+		t.Fatalf("empty stats: Mean() = %v, Variance() = %v; want 0, 0", stats.Mean(), stats.Variance())
+	}
+
+	stats.Add(3)
+	if stats.Mean() != 3 || stats.Variance() != 0 {
+		t.Fatalf("one sample: Mean() = %v, Variance() = %v; want 3, 0", stats.Mean(), stats.Variance())
+	}
+}
+
+func TestRoundTimer(t *testing.T) {
+	rounds := newRoundTimer(100)
+	for i := 0; i < 100; i++ {
+		rounds.tick()
+	}
+
+	if rounds.stats.Count() != benchmarkRounds {
+		t.Fatalf("recorded %d rounds, want %d", rounds.stats.Count(), benchmarkRounds)
+	}
+}
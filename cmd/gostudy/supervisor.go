@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// PanicError is the error a supervised worker returns in place of a panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// runRecovered calls fn, and turns a panic into a *PanicError.
+// A panic in a goroutine crashes the whole program, as no other goroutine can recover it (see Rule 48),
+// so a goroutine that must survive its work has to recover in its own deferred function.
+func runRecovered(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
+
+// Supervisor runs a worker and restarts it when it fails, like the supervisors of Erlang.
+// A worker fails by returning an error or by panicking. Between two runs it waits for a backoff
+// that doubles after every restart, so a worker that fails right away doesn't spin.
+type Supervisor struct {
+	worker      func(context.Context) error
+	maxRestarts int
+	backoff     time.Duration
+	restarts    atomic.Int64
+}
+
+// NewSupervisor returns a Supervisor restarting worker at most maxRestarts times,
+// waiting backoff before the first restart.
+func NewSupervisor(worker func(context.Context) error, maxRestarts int, backoff time.Duration) *Supervisor {
+	return &Supervisor{worker: worker, maxRestarts: maxRestarts, backoff: backoff}
+}
+
+// Run runs the worker on the calling goroutine until it returns nil, and returns nil.
+// It returns ctx.Err() once ctx is cancelled, and the last failure once the worker failed
+// after maxRestarts restarts. The worker is given ctx and must return when it is cancelled,
+// which is what makes Run return promptly: it can't stop a running worker on its own.
+func (s *Supervisor) Run(ctx context.Context) error {
+	timer := time.NewTimer(s.backoff)
+	timer.Stop()
+	defer timer.Stop()
+
+	delay := s.backoff
+	for {
+		err := runRecovered(ctx, s.worker)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		restarts := int(s.restarts.Load())
+		if restarts >= s.maxRestarts {
+			return fmt.Errorf("supervisor: worker failed after %d restarts: %w", restarts, err)
+		}
+
+		timer.Reset(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+
+		s.restarts.Add(1)
+		if debugEnabled() {
+			logger().Debug("supervisor restarted worker", slog.Int("restarts", restarts+1), slog.Any("error", err))
+		}
+	}
+}
+
+// Restarts returns how many times the worker was restarted so far.
+func (s *Supervisor) Restarts() int {
+	return int(s.restarts.Load())
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSupervisorRestartsPanickingWorker(t *testing.T) {
+	var runs atomic.Int32
+	s := NewSupervisor(func(context.Context) error {
+		if runs.Add(1) <= 2 {
+			panic("worker crashed")
+		}
+		return nil
+	}, 5, time.Millisecond)
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if n := s.Restarts(); n != 2 {
+		t.Fatalf("Restarts() = %d, want 2", n)
+	}
+	if n := runs.Load(); n != 3 {
+		t.Fatalf("worker ran %d times, want 3", n)
+	}
+}
+
+func TestSupervisorGivesUp(t *testing.T) {
+	errWorker := errors.New("worker failed")
+	var runs atomic.Int32
+	s := NewSupervisor(func(context.Context) error {
+		if runs.Add(1) == 1 {
+			panic("worker crashed")
+		}
+		return errWorker
+	}, 3, time.Millisecond)
+
+	err := s.Run(context.Background())
+
+	if !errors.Is(err, errWorker) {
+		t.Fatalf("Run() = %v, want %v", err, errWorker)
+	}
+	if n := runs.Load(); n != 4 {
+		t.Fatalf("worker ran %d times, want 4", n)
+	}
+
+	// The panic of the first run is reported as an error, with the value it panicked with.
+	err = runRecovered(context.Background(), func(context.Context) error { panic("worker crashed") })
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "worker crashed" || len(panicErr.Stack) == 0 {
+		t.Fatalf("runRecovered() = %v, want a *PanicError with the panic value and a stack", err)
+	}
+}
+
+func TestSupervisorCancelled(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 1)
+	s := NewSupervisor(func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		// A long-running worker, which fails as soon as ctx is cancelled.
+		<-ctx.Done()
+		return ctx.Err()
+	}, 10, time.Hour)
+
+	errc := make(chan error)
+	go func() {
+		errc <- s.Run(ctx)
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after ctx was cancelled")
+	}
+	if n := s.Restarts(); n != 0 {
+		t.Fatalf("Restarts() = %d after a cancellation, want 0", n)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestSupervisorCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewSupervisor(func(context.Context) error {
+		// The next run would only start after an hour.
+		time.AfterFunc(time.Millisecond, cancel)
+		return errors.New("worker failed")
+	}, 10, time.Hour)
+
+	if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Singleton lazily creates a single value of T, no matter how many goroutines ask for it.
+//
+// A common hand-rolled version is the double-checked locking below, which is a data race:
+//
+//	if s.instance == nil { // read without the lock races with the write below
+//		s.mu.Lock()
+//		if s.instance == nil {
+//			s.instance = factory()
+//		}
+//		s.mu.Unlock()
+//	}
+//	return s.instance
+//
+// The unlocked read has no happens-before edge with the write, so a goroutine may observe
+// a non-nil pointer to a value whose fields are not visible yet. sync.Once gives us that edge.
+type Singleton[T any] struct {
+	once     sync.Once
+	instance T
+}
+
+// Instance returns the value, calling factory only on the first call.
+// Factories passed to later calls are ignored.
+func (s *Singleton[T]) Instance(factory func() T) T {
+	s.once.Do(func() {
+		s.instance = factory()
+	})
+	return s.instance
+}
+
+// checkedSingleton is the double-checked locking above done right, to compare it with Singleton.
+// The flag is an atomic.Bool, and it is only set after instance is written,
+// so a goroutine that loads true is guaranteed to see the instance (the store and the load synchronize).
+// With a plain bool, or with the flag set before the write, it would be the same data race as above.
+// sync.Once is exactly this algorithm, so there is nothing to gain by writing it by hand,
+// and it is easy to get the ordering wrong.
+type checkedSingleton[T any] struct {
+	done     atomic.Bool
+	mu       sync.Mutex
+	instance T
+}
+
+func (s *checkedSingleton[T]) Instance(factory func() T) T {
+	if s.done.Load() {
+		return s.instance
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done.Load() {
+		s.instance = factory()
+		s.done.Store(true)
+	}
+	return s.instance
+}
+
+// Future is a one-shot notification that also carries a value.
+// Closing a channel wakes up every receiver at once, which makes it a broadcast,
+// and the value written before the close is visible to all of them.
+type Future[T any] struct {
+	once  sync.Once
+	done  chan struct{}
+	value T
+}
+
+func NewFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+// Set stores v and wakes up every awaiter. Only the first call has an effect.
+func (f *Future[T]) Set(v T) {
+	f.once.Do(func() {
+		f.value = v
+		close(f.done)
+	})
+}
+
+// Await blocks until Set is called, or ctx is cancelled.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// ChanMutex is a lock built from a channel with a capacity of 1.
+// Holding the lock means owning the single slot of the buffer.
+// Because acquiring it is a channel send, it composes with select,
+// which gives TryLock and cancellation almost for free.
+type ChanMutex struct {
+	slot chan struct{}
+}
+
+func NewChanMutex() *ChanMutex {
+	return &ChanMutex{slot: make(chan struct{}, 1)}
+}
+
+func (m *ChanMutex) Lock() {
+	m.slot <- struct{}{}
+}
+
+// Unlock releases the lock. Like sync.Mutex, unlocking an unlocked ChanMutex is a bug:
+// a plain receive would block forever on the empty slot, so it panics instead.
+func (m *ChanMutex) Unlock() {
+	select {
+	case <-m.slot:
+	default:
+		panic("unlock of unlocked ChanMutex")
+	}
+}
+
+// TryLock acquires the lock if it is free, and reports whether it did.
+func (m *ChanMutex) TryLock() bool {
+	select {
+	case m.slot <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// LockCtx waits for the lock until ctx is cancelled.
+// It returns ctx.Err() if the lock wasn't acquired.
+func (m *ChanMutex) LockCtx(ctx context.Context) error {
+	select {
+	case m.slot <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Barrier is a cyclic barrier: Wait blocks until n goroutines have called it,
+// then releases all of them at once and resets for the next phase.
+// Unlike a sync.WaitGroup, it can be reused without waiting for every goroutine to leave the previous phase.
+// Every phase gets its own channel, which is closed to release the goroutines waiting on it,
+// so a fast goroutine that already waits on the next phase can't be released by the previous one.
+type Barrier struct {
+	n       int
+	mu      sync.Mutex
+	arrived int
+	release chan struct{}
+}
+
+// NewBarrier panics if n is less than 1, as no number of calls to Wait could release such a barrier.
+func NewBarrier(n int) *Barrier {
+	if n < 1 {
+		panic("NewBarrier: n must be at least 1")
+	}
+	return &Barrier{n: n, release: make(chan struct{})}
+}
+
+func (b *Barrier) Wait() {
+	b.mu.Lock()
+	release := b.release
+	b.arrived++
+	if b.arrived == b.n {
+		b.arrived = 0
+		b.release = make(chan struct{})
+		b.mu.Unlock()
+		close(release)
+		return
+	}
+	b.mu.Unlock()
+
+	<-release
+}
+
+// WaitAll waits until every channel of dones is closed, and returns ctx.Err() if ctx is cancelled first.
+// The order of the waits doesn't matter: a done channel that is already closed is passed immediately,
+// so waiting on each of them in turn takes as long as the slowest one, without starting any goroutine.
+func WaitAll(ctx context.Context, dones ...<-chan struct{}) error {
+	for _, done := range dones {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// SleepCtx pauses the calling goroutine for d, like time.Sleep, but returns ctx.Err() as soon as ctx is cancelled.
+// It returns nil if the whole duration elapsed. time.After would do in the select, and since Go 1.23
+// its timer is collected once unreferenced, but stopping the timer on the way out releases it right away,
+// without leaving a pending timer for the runtime to track until the GC gets to it.
+func SleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// JoinWithTimeout waits for wg up to d, and reports whether it completed in time.
+// A WaitGroup can't be waited on with a select, so a helper goroutine calls Wait and closes a channel.
+// That goroutine can't be cancelled: after a timeout it is left behind, blocked in Wait until wg completes,
+// and forever if wg never does. So the caller should also tell the goroutines wg waits for to stop,
+// e.g. by cancelling their context, which lets the helper goroutine exit soon after.
+func JoinWithTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Memoizer caches the result of an expensive function per key, and computes it only once per key,
+// even when several goroutines ask for the same key at the same time: the first caller runs fn,
+// and the others wait for its result instead of computing it again (like golang.org/x/sync/singleflight,
+// but the result is kept). The zero value is ready to use.
+//
+// The lock only protects the map, so computations of different keys run concurrently.
+// Each key gets its own done channel, closed once fn returned, which also makes the waiting callers
+// see the value written by fn. If fn panics, nothing is cached: the panic goes on to its caller,
+// and the callers waiting for the key try again, so one of them runs its own fn.
+// A sync.Once would not do here, as it considers a panicking function done.
+type Memoizer[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*memoEntry[V]
+}
+
+type memoEntry[V any] struct {
+	done     chan struct{}
+	value    V
+	panicked bool // written before done is closed
+}
+
+// Do returns the value of key, calling fn to compute it if no earlier call did.
+func (m *Memoizer[K, V]) Do(key K, fn func() V) V {
+	for {
+		m.mu.Lock()
+		if m.entries == nil {
+			m.entries = make(map[K]*memoEntry[V])
+		}
+		entry, ok := m.entries[key]
+		if !ok {
+			entry = &memoEntry[V]{done: make(chan struct{})}
+			m.entries[key] = entry
+			m.mu.Unlock()
+			return m.compute(key, entry, fn)
+		}
+		m.mu.Unlock()
+
+		<-entry.done
+		if !entry.panicked {
+			return entry.value
+		}
+	}
+}
+
+// compute runs fn for the entry of key it just added, and removes the entry again if fn panics.
+func (m *Memoizer[K, V]) compute(key K, entry *memoEntry[V], fn func() V) V {
+	completed := false
+	defer func() {
+		if !completed {
+			m.mu.Lock()
+			delete(m.entries, key)
+			m.mu.Unlock()
+			entry.panicked = true
+		}
+		close(entry.done)
+	}()
+
+	entry.value = fn()
+	completed = true
+	return entry.value
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingletonInstance(t *testing.T) {
+	type config struct {
+		name string
+	}
+
+	for _, testcase := range []struct {
+		name      string
+		singleton interface {
+			Instance(factory func() *config) *config
+		}
+	}{
+		{"Once", &Singleton[*config]{}},
+		{"DoubleChecked", &checkedSingleton[*config]{}},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			var calls atomic.Int32
+			factory := func() *config {
+				calls.Add(1)
+				return &config{name: "default"}
+			}
+
+			const goroutines = 100
+			instances := make([]*config, goroutines)
+			wg := sync.WaitGroup{}
+			wg.Add(goroutines)
+			for i := 0; i < goroutines; i++ {
+				go func() {
+					defer wg.Done()
+					instances[i] = testcase.singleton.Instance(factory)
+				}()
+			}
+			wg.Wait()
+
+			if n := calls.Load(); n != 1 {
+				t.Fatalf("factory called %d times, want 1", n)
+			}
+			for i, instance := range instances {
+				if instance != instances[0] || instance.name != "default" {
+					t.Fatalf("instance %d is %p, want %p", i, instance, instances[0])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSingletonOnce and BenchmarkSingletonDoubleChecked measure the fast path, once the value exists.
+// Both are an atomic load and a branch, so sync.Once costs nothing compared to the hand-rolled version.
+func BenchmarkSingletonOnce(b *testing.B) {
+	var s Singleton[int64]
+	factory := func() int64 { return 42 }
+	s.Instance(factory)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Consume(s.Instance(factory))
+		}
+	})
+}
+
+func BenchmarkSingletonDoubleChecked(b *testing.B) {
+	var s checkedSingleton[int64]
+	factory := func() int64 { return 42 }
+	s.Instance(factory)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Consume(s.Instance(factory))
+		}
+	})
+}
+
+func TestFuture(t *testing.T) {
+	future := NewFuture[int]()
+
+	const awaiters = 50
+	values := make([]int, awaiters)
+	errs := make([]error, awaiters)
+	wg := sync.WaitGroup{}
+	wg.Add(awaiters)
+	for i := 0; i < awaiters; i++ {
+		go func() {
+			defer wg.Done()
+			values[i], errs[i] = future.Await(context.Background())
+		}()
+	}
+
+	future.Set(42)
+	future.Set(7)
+	wg.Wait()
+
+	for i := 0; i < awaiters; i++ {
+		if errs[i] != nil || values[i] != 42 {
+			t.Fatalf("Await() = %d, %v; want 42, nil", values[i], errs[i])
+		}
+	}
+}
+
+func TestFutureAwaitCancelled(t *testing.T) {
+	future := NewFuture[string]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	value, err := future.Await(ctx)
+
+	if !errors.Is(err, context.DeadlineExceeded) || value != "" {
+		t.Fatalf("Await() = %q, %v; want \"\", %v", value, err, context.DeadlineExceeded)
+	}
+}
+
+func TestChanMutexMutualExclusion(t *testing.T) {
+	m := NewChanMutex()
+	var inside atomic.Bool
+	counter := 0
+
+	const goroutines = 20
+	wg := sync.WaitGroup{}
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Lock()
+				if !inside.CompareAndSwap(false, true) {
+					t.Error("two goroutines hold the lock at the same time")
+				}
+				counter++
+				inside.Store(false)
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counter != goroutines*100 {
+		t.Fatalf("counter = %d, want %d", counter, goroutines*100)
+	}
+}
+
+func TestChanMutexTryLock(t *testing.T) {
+	m := NewChanMutex()
+
+	if !m.TryLock() {
+		t.Fatal("TryLock() on a free mutex = false, want true")
+	}
+	if m.TryLock() {
+		t.Fatal("TryLock() on a held mutex = true, want false")
+	}
+	m.Unlock()
+	if !m.TryLock() {
+		t.Fatal("TryLock() after Unlock = false, want true")
+	}
+}
+
+func TestChanMutexUnlockUnlocked(t *testing.T) {
+	m := NewChanMutex()
+	m.Lock()
+	m.Unlock()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Unlock() of an unlocked ChanMutex didn't panic")
+		}
+	}()
+	m.Unlock()
+}
+
+func TestChanMutexLockCtx(t *testing.T) {
+	m := NewChanMutex()
+	m.Lock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		errc <- m.LockCtx(ctx)
+	}()
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("LockCtx() = %v, want %v", err, context.Canceled)
+	}
+
+	m.Unlock()
+	if err := m.LockCtx(context.Background()); err != nil {
+		t.Fatalf("LockCtx() on a free mutex = %v, want nil", err)
+	}
+}
+
+func TestBarrier(t *testing.T) {
+	const goroutines = 8
+	const phases = 3
+
+	barrier := NewBarrier(goroutines)
+	var finished [phases]atomic.Int64
+
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for phase := 0; phase < phases; phase++ {
+				finished[phase].Add(1)
+				barrier.Wait()
+				// Nobody can pass the barrier before every goroutine has finished the phase.
+				if n := finished[phase].Load(); n != goroutines {
+					t.Errorf("passed the barrier of phase %d after %d goroutines finished it, want %d", phase, n, goroutines)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewBarrierInvalidSize(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("NewBarrier(%d) didn't panic", n)
+				}
+			}()
+			NewBarrier(n)
+		}()
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	t.Run("AllClosed", func(t *testing.T) {
+		dones := make([]chan struct{}, 3)
+		for i := range dones {
+			dones[i] = make(chan struct{})
+		}
+		// Closed in the reverse order of the waits.
+		go func() {
+			for i := len(dones) - 1; i >= 0; i-- {
+				time.Sleep(time.Millisecond)
+				close(dones[i])
+			}
+		}()
+
+		if err := WaitAll(context.Background(), dones[0], dones[1], dones[2]); err != nil {
+			t.Fatalf("WaitAll() = %v, want nil", err)
+		}
+	})
+
+	t.Run("PartialThenCancel", func(t *testing.T) {
+		closed := make(chan struct{})
+		close(closed)
+		open := make(chan struct{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if err := WaitAll(ctx, closed, open); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("WaitAll() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Nothing to wait for, so even a cancelled context doesn't make it fail.
+		if err := WaitAll(ctx); err != nil {
+			t.Fatalf("WaitAll() = %v, want nil", err)
+		}
+	})
+}
+
+func TestJoinWithTimeout(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	const d = 20 * time.Millisecond
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	start := time.Now()
+	if JoinWithTimeout(&wg, d) {
+		t.Fatal("JoinWithTimeout() = true for a WaitGroup that never completes, want false")
+	}
+	if elapsed := time.Since(start); elapsed < d {
+		t.Fatalf("JoinWithTimeout() returned after %v, want at least %v", elapsed, d)
+	}
+
+	// The waiter goroutine is still blocked in Wait, and exits once the WaitGroup completes.
+	wg.Done()
+	waitForGoroutines(t, baseline)
+
+	// A WaitGroup must not be reused before every Wait on it has returned, so this uses a new one.
+	var completing sync.WaitGroup
+	completing.Add(1)
+	go func() {
+		time.Sleep(time.Millisecond)
+		completing.Done()
+	}()
+	if !JoinWithTimeout(&completing, time.Second) {
+		t.Fatal("JoinWithTimeout() = false for a WaitGroup that completes, want true")
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestMemoizer(t *testing.T) {
+	var m Memoizer[string, *int]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	compute := func() *int {
+		calls.Add(1)
+		// Keeps the computation running until every goroutine asked for the key.
+		<-release
+		v := 42
+		return &v
+	}
+
+	const goroutines = 100
+	values := make([]*int, goroutines)
+	var started, wg sync.WaitGroup
+	started.Add(goroutines)
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			started.Done()
+			values[i] = m.Do("answer", compute)
+		}()
+	}
+	started.Wait()
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fn called %d times, want 1", n)
+	}
+	for i, v := range values {
+		if v != values[0] || *v != 42 {
+			t.Fatalf("caller %d got %p, want %p", i, v, values[0])
+		}
+	}
+
+	// Another key is computed separately, and the first one stays cached.
+	other := 7
+	if v := m.Do("other", func() *int { return &other }); v != &other {
+		t.Fatalf("Do(other) = %p, want %p", v, &other)
+	}
+	if v := m.Do("answer", compute); v != values[0] || calls.Load() != 1 {
+		t.Fatalf("Do(answer) = %p after %d calls, want the cached %p", v, calls.Load(), values[0])
+	}
+}
+
+func TestMemoizerPanic(t *testing.T) {
+	var m Memoizer[string, int]
+	started := make(chan struct{})
+
+	// A caller waiting for the key while fn panics tries again with its own fn.
+	waited := make(chan int)
+	go func() {
+		<-started
+		waited <- m.Do("answer", func() int { return 42 })
+	}()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "computation failed" {
+				t.Errorf("recovered %v, want the panic of fn", r)
+			}
+		}()
+		m.Do("answer", func() int {
+			close(started)
+			// Gives the other caller time to start waiting for the key. If it is late,
+			// it finds no entry and computes the key itself, which gives the same result.
+			time.Sleep(10 * time.Millisecond)
+			panic("computation failed")
+		})
+	}()
+
+	if v := <-waited; v != 42 {
+		t.Fatalf("Do() of a waiting caller = %d, want 42", v)
+	}
+	if v := m.Do("answer", func() int { return 7 }); v != 42 {
+		t.Fatalf("Do() after the retry = %d, want the cached 42", v)
+	}
+}
+
+func TestSleepCtx(t *testing.T) {
+	const d = 10 * time.Millisecond
+
+	start := time.Now()
+	if err := SleepCtx(context.Background(), d); err != nil {
+		t.Fatalf("SleepCtx() = %v, want nil", err)
+	}
+	if elapsed := time.Since(start); elapsed < d {
+		t.Fatalf("SleepCtx() returned after %v, want at least %v", elapsed, d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond, cancel)
+	start = time.Now()
+	if err := SleepCtx(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SleepCtx() = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("SleepCtx() returned %v after the cancellation", elapsed)
+	}
+}
+
+// TestSleepCtxDoesNotPileUp cancels many long sleeps, and checks that nothing of them is left behind:
+// neither a goroutine still sleeping for the full duration, as a SleepCtx built on a goroutine and
+// time.Sleep would leave, nor anything that keeps the timers reachable after a GC.
+func TestSleepCtxDoesNotPileUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	baseline := runtime.NumGoroutine()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	const sleeps = 10000
+	for i := 0; i < sleeps; i++ {
+		if err := SleepCtx(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+			t.Fatalf("SleepCtx() = %v, want %v", err, context.Canceled)
+		}
+	}
+
+	if n := runtime.NumGoroutine(); n > baseline {
+		t.Fatalf("%d goroutines after %d cancelled sleeps, want at most %d", n, sleeps, baseline)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	if grown := int64(after.HeapObjects) - int64(before.HeapObjects); grown > sleeps/10 {
+		t.Fatalf("heap grew by %d objects after %d cancelled sleeps, want the timers released", grown, sleeps)
+	}
+}
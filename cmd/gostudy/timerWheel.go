@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+type wheelTimer struct {
+	deadline int // the tick, counted from the start of the wheel, at which the timer fires
+	ch       chan struct{}
+}
+
+// TimerWheel schedules many timeouts with a single goroutine and a single ticker.
+// time.After creates a runtime timer per call, and thousands of pending timeouts mean thousands of timers.
+// A hashed wheel instead puts every timeout into one of size slots, and on each tick only
+// looks at the slot under the hand. Timeouts further away than one revolution stay in their slot
+// until the hand comes around again.
+//
+// The price is precision: a timeout fires up to one tick after its duration.
+type TimerWheel struct {
+	tick  time.Duration
+	start time.Time
+	mu    sync.Mutex
+	slots [][]wheelTimer
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewTimerWheel starts a wheel with the given tick and number of slots. Stop must be called to release it.
+// NewTimerWheel panics if tick isn't positive or size is less than 1.
+func NewTimerWheel(tick time.Duration, size int) *TimerWheel {
+	if tick <= 0 {
+		panic("NewTimerWheel: tick must be positive")
+	}
+	if size < 1 {
+		panic("NewTimerWheel: size must be at least 1")
+	}
+	w := &TimerWheel{
+		tick:  tick,
+		start: time.Now(),
+		slots: make([][]wheelTimer, size),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *TimerWheel) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+
+	// A Ticker drops ticks when its receiver falls behind, e.g. when the CPUs are busy.
+	// Counting the ticks from the wall clock lets the hand catch up instead of drifting.
+	hand := 0
+	for {
+		select {
+		case now := <-ticker.C:
+			for due := int(now.Sub(w.start) / w.tick); hand < due; {
+				hand++
+				w.advance(hand)
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// advance fires the timers of the slot under the hand that are due at tick.
+func (w *TimerWheel) advance(tick int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	pos := tick % len(w.slots)
+	slot := w.slots[pos]
+	remaining := slot[:0]
+	for _, timer := range slot {
+		if timer.deadline <= tick {
+			close(timer.ch)
+			continue
+		}
+		remaining = append(remaining, timer)
+	}
+	// Clear the tail, so fired channels can be collected.
+	clear(slot[len(remaining):])
+	w.slots[pos] = remaining
+}
+
+// After returns a channel that is closed once d has passed, and at most one tick later.
+// Channels of timeouts still pending when the wheel is stopped are never closed.
+func (w *TimerWheel) After(d time.Duration) <-chan struct{} {
+	// The current tick is already partially over, so one more tick is needed to never fire early.
+	// The deadline is based on the wall clock rather than on the hand, which may be lagging behind.
+	deadline := int(time.Since(w.start)/w.tick) + int((d+w.tick-1)/w.tick) + 1
+
+	ch := make(chan struct{})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	slot := deadline % len(w.slots)
+	w.slots[slot] = append(w.slots[slot], wheelTimer{deadline: deadline, ch: ch})
+	return ch
+}
+
+// Stop stops the wheel's goroutine. It is safe to call more than once.
+func (w *TimerWheel) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
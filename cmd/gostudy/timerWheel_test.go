@@ -0,0 +1,94 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimerWheel(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	const tick = time.Millisecond
+	// Scheduling 10000 goroutines on a busy CI machine adds its own delay on top of the tick.
+	const tolerance = 100 * time.Millisecond
+
+	w := NewTimerWheel(tick, 64)
+
+	const timeouts = 10000
+	var early, late atomic.Int32
+	wg := sync.WaitGroup{}
+	wg.Add(timeouts)
+	for i := 0; i < timeouts; i++ {
+		// Up to 200ms spans the 64 slots about three times, so multi-round timeouts are covered too.
+		d := time.Duration(i%200) * time.Millisecond
+		start := time.Now()
+		ch := w.After(d)
+		go func() {
+			defer wg.Done()
+			<-ch
+			elapsed := time.Since(start)
+			if elapsed < d {
+				early.Add(1)
+			}
+			if elapsed > d+tick+tolerance {
+				late.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	w.Stop()
+
+	if early.Load() != 0 {
+		t.Fatalf("%d timeouts fired before their duration", early.Load())
+	}
+	if late.Load() != 0 {
+		t.Fatalf("%d timeouts fired later than %v after their duration", late.Load(), tick+tolerance)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+// BenchmarkTimerWheelAfter schedules a timeout on the wheel. It allocates the channel, and now and then
+// grows a slot, but no runtime timer.
+func BenchmarkTimerWheelAfter(b *testing.B) {
+	w := NewTimerWheel(time.Millisecond, 512)
+	defer w.Stop()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.After(time.Second)
+	}
+}
+
+// BenchmarkTimeAfter creates a runtime timer and its channel for every timeout.
+func BenchmarkTimeAfter(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		time.After(time.Second)
+	}
+}
+
+func TestNewTimerWheelInvalidArguments(t *testing.T) {
+	for _, testcase := range []struct {
+		name string
+		tick time.Duration
+		size int
+	}{
+		{"ZeroTick", 0, 8},
+		{"NegativeTick", -time.Millisecond, 8},
+		{"ZeroSize", time.Millisecond, 0},
+		{"NegativeSize", time.Millisecond, -1},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("NewTimerWheel(%v, %d) didn't panic", testcase.tick, testcase.size)
+				}
+			}()
+			NewTimerWheel(testcase.tick, testcase.size)
+		})
+	}
+}
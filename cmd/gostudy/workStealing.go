@@ -0,0 +1,149 @@
+package main
+
+import "sync"
+
+// workStealingChunk is the number of inputs in a unit of work.
+const workStealingChunk = 1024
+
+// chunkDeque holds the chunk indices of one worker.
+// The owner pops from the back, and thieves steal from the front,
+// so they only compete for the same chunk when a single one is left.
+type chunkDeque struct {
+	mu     sync.Mutex
+	chunks []int
+}
+
+func (d *chunkDeque) pop() (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.chunks) == 0 {
+		return 0, false
+	}
+	chunk := d.chunks[len(d.chunks)-1]
+	d.chunks = d.chunks[:len(d.chunks)-1]
+	return chunk, true
+}
+
+func (d *chunkDeque) steal() (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.chunks) == 0 {
+		return 0, false
+	}
+	chunk := d.chunks[0]
+	d.chunks = d.chunks[1:]
+	return chunk, true
+}
+
+// paddedResult fills a whole cache line, so workers writing their own partial sums
+// next to each other don't invalidate each other's lines (see FastResult).
+type paddedResult struct {
+	Result
+	_ [48]byte
+}
+
+// SumWorkStealing sums inputs with workers goroutines.
+// Static partitioning finishes only when the unluckiest worker does.
+// Here, a worker that runs out of chunks steals from the others, so nobody idles while work is left.
+func SumWorkStealing(inputs []Input, workers int) Result {
+	return sumWorkStealing(inputs, workers, sumInputs)
+}
+
+func sumInputs(inputs []Input) Result {
+	result := Result{}
+	for i := 0; i < len(inputs); i++ {
+		result.sumA += inputs[i].a
+		result.sumB += inputs[i].b
+	}
+	return result
+}
+
+func chunkBounds(chunk, size int) (int, int) {
+	start := chunk * workStealingChunk
+	end := start + workStealingChunk
+	if end > size {
+		end = size
+	}
+	return start, end
+}
+
+// sumWorkStealing takes the function summing a chunk, so that benchmarks can make some chunks slower.
+func sumWorkStealing(inputs []Input, workers int, sumChunk func([]Input) Result) Result {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Deal out contiguous ranges of chunks, just like static partitioning would.
+	chunks := (len(inputs) + workStealingChunk - 1) / workStealingChunk
+	deques := make([]chunkDeque, workers)
+	for w := 0; w < workers; w++ {
+		for chunk := w * chunks / workers; chunk < (w+1)*chunks/workers; chunk++ {
+			deques[w].chunks = append(deques[w].chunks, chunk)
+		}
+	}
+
+	partials := make([]paddedResult, workers)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				chunk, ok := deques[w].pop()
+				// Nothing creates new chunks, so once every deque is empty, the work is done.
+				for victim := 1; !ok && victim < workers; victim++ {
+					chunk, ok = deques[(w+victim)%workers].steal()
+				}
+				if !ok {
+					return
+				}
+
+				start, end := chunkBounds(chunk, len(inputs))
+				partial := sumChunk(inputs[start:end])
+				partials[w].sumA += partial.sumA
+				partials[w].sumB += partial.sumB
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := Result{}
+	for w := 0; w < workers; w++ {
+		result.sumA += partials[w].sumA
+		result.sumB += partials[w].sumB
+	}
+	return result
+}
+
+// sumStaticPartition splits the chunks evenly across workers up front, for comparison.
+func sumStaticPartition(inputs []Input, workers int, sumChunk func([]Input) Result) Result {
+	if workers < 1 {
+		workers = 1
+	}
+
+	chunks := (len(inputs) + workStealingChunk - 1) / workStealingChunk
+	partials := make([]paddedResult, workers)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for chunk := w * chunks / workers; chunk < (w+1)*chunks/workers; chunk++ {
+				start, end := chunkBounds(chunk, len(inputs))
+				partial := sumChunk(inputs[start:end])
+				partials[w].sumA += partial.sumA
+				partials[w].sumB += partial.sumB
+			}
+		}()
+	}
+	wg.Wait()
+
+	result := Result{}
+	for w := 0; w < workers; w++ {
+		result.sumA += partials[w].sumA
+		result.sumB += partials[w].sumB
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newInputs(size int) []Input {
+	inputs := make([]Input, size)
+	for i := 0; i < size; i++ {
+		inputs[i] = Input{a: int64(i), b: int64(i * 2)}
+	}
+	return inputs
+}
+
+func TestSumWorkStealing(t *testing.T) {
+	for _, size := range []int{0, 1, workStealingChunk, 10*workStealingChunk + 17} {
+		inputs := newInputs(size)
+		want := sumInputs(inputs)
+
+		for _, workers := range []int{1, 3, 8} {
+			if got := SumWorkStealing(inputs, workers); got != want {
+				t.Fatalf("SumWorkStealing(%d inputs, %d workers) = %+v, want %+v", size, workers, got, want)
+			}
+		}
+	}
+}
+
+// skewedSum makes the chunks at the start of the input slow, as if their data was more expensive.
+// With static partitioning, they all land on the first worker.
+func skewedSum(inputs []Input) Result {
+	if inputs[0].a < 8*workStealingChunk {
+		time.Sleep(200 * time.Microsecond)
+	}
+	return sumInputs(inputs)
+}
+
+// BenchmarkSumStaticPartitionSkewed and BenchmarkSumWorkStealingSkewed sum the same skewed input with 8 workers.
+// The static version takes as long as the first worker needs for all 8 slow chunks,
+// while work stealing spreads the slow chunks across the idle workers.
+func BenchmarkSumStaticPartitionSkewed(b *testing.B) {
+	inputs := newInputs(64 * workStealingChunk)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := sumStaticPartition(inputs, 8, skewedSum)
+		Consume(r.sumA + r.sumB)
+	}
+}
+
+func BenchmarkSumWorkStealingSkewed(b *testing.B) {
+	inputs := newInputs(64 * workStealingChunk)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := sumWorkStealing(inputs, 8, skewedSum)
+		Consume(r.sumA + r.sumB)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrForcedShutdown is returned by WorkerPool.CloseTimeout when the workers had to be stopped.
+var ErrForcedShutdown = errors.New("worker pool: shutdown timed out, workers were stopped")
+
+// job is a unit of work for the WorkerPool.
+// If reply is nil, the output goes to the shared results channel.
+type job struct {
+	input int
+	reply chan<- int
+}
+
+// WorkerPool runs fn on submitted inputs with a fixed number of goroutines.
+// Spawning one goroutine per input is cheap, but not free, and it doesn't bound the concurrency.
+type WorkerPool struct {
+	fn      func(int) int
+	jobs    chan job
+	results chan int
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+
+	submitted atomic.Int64
+	started   atomic.Int64
+	completed atomic.Int64
+	active    atomic.Int64
+}
+
+// PoolStats is a snapshot of the activity of a WorkerPool.
+type PoolStats struct {
+	SubmittedJobs int64 // jobs passed to Submit or SubmitFuture, including those still waiting to be picked up
+	CompletedJobs int64 // jobs whose fn returned
+	QueueLength   int64 // submitted jobs no worker has picked up yet
+	ActiveWorkers int64 // workers currently running fn
+}
+
+// NewWorkerPool starts workers goroutines that apply fn to every submitted input.
+// Close must be called to stop them.
+func NewWorkerPool(workers int, fn func(int) int) *WorkerPool {
+	p := &WorkerPool{
+		fn:      fn,
+		jobs:    make(chan job),
+		results: make(chan int),
+		stop:    make(chan struct{}),
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	logger().Debug("pool worker started")
+	defer logger().Debug("pool worker stopped")
+
+	for j := range p.jobs {
+		p.started.Add(1)
+		p.active.Add(1)
+		output := p.fn(j.input)
+		p.active.Add(-1)
+		p.completed.Add(1)
+		if j.reply != nil {
+			j.reply <- output
+			continue
+		}
+		select {
+		case p.results <- output:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Submit queues input, and its output is delivered on Results.
+// Outputs arrive in completion order, so they can't be matched back to their inputs.
+func (p *WorkerPool) Submit(input int) {
+	p.submitted.Add(1)
+	p.jobs <- job{input: input}
+}
+
+// SubmitFuture queues input, and returns a channel that receives the output of this input only.
+// This lets callers correlate outputs with inputs, as in scatter-gather.
+// The returned channel is buffered, so the worker never waits for the caller to receive.
+func (p *WorkerPool) SubmitFuture(input int) <-chan int {
+	reply := make(chan int, 1)
+	p.submitted.Add(1)
+	p.jobs <- job{input: input, reply: reply}
+	return reply
+}
+
+// Stats returns the current counters of the pool. It only reads atomics, so it never contends with the workers.
+// The counters are read separately, so the snapshot isn't exact while jobs are running,
+// but they are read in the reverse order of their updates (a job is submitted, then started, then completed),
+// so a counter is never behind the one derived from it, and QueueLength never goes negative.
+// ActiveWorkers has its own gauge, as the difference of two counters read at different times could exceed the workers.
+func (p *WorkerPool) Stats() PoolStats {
+	completed := p.completed.Load()
+	started := p.started.Load()
+	submitted := p.submitted.Load()
+	return PoolStats{
+		SubmittedJobs: submitted,
+		CompletedJobs: completed,
+		QueueLength:   submitted - started,
+		ActiveWorkers: p.active.Load(),
+	}
+}
+
+// Results returns the outputs of inputs queued with Submit.
+// It is closed once the pool is closed and every worker has returned.
+func (p *WorkerPool) Results() <-chan int {
+	return p.results
+}
+
+// Close stops accepting jobs, waits for the queued ones to finish, and closes Results.
+// Results must be drained concurrently if Submit was used, otherwise the workers can't finish.
+// Submitting after Close panics.
+func (p *WorkerPool) Close() {
+	p.once.Do(func() {
+		close(p.jobs)
+		p.wg.Wait()
+		close(p.results)
+	})
+}
+
+// CloseTimeout is Close with a bound on the wait, like http.Server.Shutdown.
+// It stops accepting jobs and waits up to d for the in-flight ones.
+// After that, it tells the workers to stop: a worker waiting to deliver an output on Results drops it,
+// and a worker running fn returns as soon as fn does. CloseTimeout waits up to d again for the workers
+// to exit, and returns ErrForcedShutdown, so it returns within about 2*d even if fn hangs.
+// Results is closed once every worker has returned, which may be after CloseTimeout returns
+// if a worker is still running fn.
+func (p *WorkerPool) CloseTimeout(d time.Duration) error {
+	var err error
+	p.once.Do(func() {
+		close(p.jobs)
+
+		drained := make(chan struct{})
+		go func() {
+			p.wg.Wait()
+			close(p.results)
+			close(drained)
+		}()
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-drained:
+			return
+		case <-timer.C:
+		}
+
+		close(p.stop)
+		err = ErrForcedShutdown
+		timer.Reset(d)
+		select {
+		case <-drained:
+		case <-timer.C:
+			// A worker is stuck in fn, which can't be interrupted.
+		}
+	})
+	return err
+}
+
+// runPerGoroutine spawns one goroutine per job and waits for all of them.
+// It returns the number of processed jobs.
+func runPerGoroutine(jobs int, fn func(int) int) int {
+	var processed atomic.Int64
+
+	wg := sync.WaitGroup{}
+	wg.Add(jobs)
+	for i := 0; i < jobs; i++ {
+		go func() {
+			defer wg.Done()
+			Consume(int64(fn(i)))
+			processed.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	return int(processed.Load())
+}
+
+// runPooled submits the jobs to an existing pool and waits for their outputs.
+// It returns the number of processed jobs.
+func runPooled(p *WorkerPool, jobs int) int {
+	go func() {
+		for i := 0; i < jobs; i++ {
+			p.Submit(i)
+		}
+	}()
+
+	processed := 0
+	for i := 0; i < jobs; i++ {
+		Consume(int64(<-p.Results()))
+		processed++
+	}
+	return processed
+}
+
+// PriorityWorkerPool is a WorkerPool whose waiting jobs are dispatched by priority instead of arrival order.
+// A channel is FIFO, so the jobs wait in a PriorityQueue, and a buffered channel only carries
+// one token per queued job to wake up the workers.
+type PriorityWorkerPool struct {
+	fn      func(int) int
+	queue   PriorityQueue[int]
+	slots   chan struct{}
+	ready   chan struct{}
+	results chan int
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewPriorityWorkerPool starts workers goroutines. At most queueSize jobs can wait for a worker,
+// and Submit blocks beyond that.
+// NewPriorityWorkerPool panics if queueSize is less than 1: the jobs have to wait in the queue
+// before a worker picks them up, so without any room the first Submit would block forever.
+func NewPriorityWorkerPool(workers, queueSize int, fn func(int) int) *PriorityWorkerPool {
+	if queueSize < 1 {
+		panic("NewPriorityWorkerPool: queueSize must be at least 1")
+	}
+	p := &PriorityWorkerPool{
+		fn:      fn,
+		slots:   make(chan struct{}, queueSize),
+		ready:   make(chan struct{}, queueSize),
+		results: make(chan int),
+	}
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *PriorityWorkerPool) work() {
+	defer p.wg.Done()
+
+	// Every token was sent after its job was pushed, so Pop always finds a job.
+	for range p.ready {
+		input, _ := p.queue.Pop()
+		<-p.slots
+		p.results <- p.fn(input)
+	}
+}
+
+// Submit queues input with the given priority. Higher priorities are dispatched first.
+func (p *PriorityWorkerPool) Submit(input, priority int) {
+	p.slots <- struct{}{}
+	p.queue.Push(input, priority)
+	p.ready <- struct{}{}
+}
+
+// Results returns the outputs, and is closed once the pool is closed and the workers returned.
+func (p *PriorityWorkerPool) Results() <-chan int {
+	return p.results
+}
+
+// Close stops accepting jobs, waits for the queued ones to finish, and closes Results.
+// Results must be drained concurrently, and submitting after Close panics.
+func (p *PriorityWorkerPool) Close() {
+	p.once.Do(func() {
+		close(p.ready)
+		p.wg.Wait()
+		close(p.results)
+	})
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolResults(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	pool := NewWorkerPool(4, func(v int) int { return v * 2 })
+
+	var got []int
+	done := make(chan struct{})
+	go func() {
+		for output := range pool.Results() {
+			got = append(got, output)
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 10; i++ {
+		pool.Submit(i)
+	}
+	pool.Close()
+	<-done
+
+	sort.Ints(got)
+	for i, output := range got {
+		if output != i*2 {
+			t.Fatalf("outputs = %v, want doubled inputs 0..9", got)
+		}
+	}
+	if len(got) != 10 {
+		t.Fatalf("received %d outputs, want 10", len(got))
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestWorkerPoolSubmitFuture(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	pool := NewWorkerPool(4, func(v int) int {
+		// Random delays make the workers finish out of submission order.
+		time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+		return v * v
+	})
+
+	const jobs = 50
+	futures := make([]<-chan int, jobs)
+	for i := 0; i < jobs; i++ {
+		futures[i] = pool.SubmitFuture(i)
+	}
+
+	for i, future := range futures {
+		if output := <-future; output != i*i {
+			t.Fatalf("output of input %d = %d, want %d", i, output, i*i)
+		}
+	}
+
+	pool.Close()
+	waitForGoroutines(t, baseline)
+}
+
+func TestRunPerGoroutineAndPooledProcessSameJobs(t *testing.T) {
+	const jobs = 1000
+	square := func(v int) int { return v * v }
+
+	pool := NewWorkerPool(4, square)
+	defer pool.Close()
+
+	if n := runPerGoroutine(jobs, square); n != jobs {
+		t.Fatalf("runPerGoroutine() processed %d jobs, want %d", n, jobs)
+	}
+	if n := runPooled(pool, jobs); n != jobs {
+		t.Fatalf("runPooled() processed %d jobs, want %d", n, jobs)
+	}
+}
+
+// BenchmarkGoroutinePerJob starts and joins a goroutine for every job.
+// A goroutine starts with a 2KB stack and has to be scheduled, which is cheap but not free,
+// and allocs/op shows the closure allocated for every one of them.
+func BenchmarkGoroutinePerJob(b *testing.B) {
+	const jobs = 1000
+	square := func(v int) int { return v * v }
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		runPerGoroutine(jobs, square)
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*jobs), "ns/job")
+}
+
+// BenchmarkWorkerPoolJob hands every job to long-lived workers instead.
+// The handoff through two unbuffered channels isn't free either, so the pool mainly wins
+// by bounding the number of goroutines rather than by being faster for tiny jobs.
+func BenchmarkWorkerPoolJob(b *testing.B) {
+	const jobs = 1000
+	square := func(v int) int { return v * v }
+
+	pool := NewWorkerPool(runtime.GOMAXPROCS(0), square)
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		runPooled(pool, jobs)
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*jobs), "ns/job")
+}
+
+// runPooledJobs starts a pool of workers goroutines, runs jobs on it, and closes it.
+func runPooledJobs(workers, jobs int, fn func(int) int) int {
+	pool := NewWorkerPool(workers, fn)
+	processed := runPooled(pool, jobs)
+	pool.Close()
+	return processed
+}
+
+func TestWorkerPoolCapsGoroutines(t *testing.T) {
+	const workers, jobs = 4, 1000
+	work := func(v int) int { return int(mix(int64(v))) }
+
+	processed := 0
+	peak := MeasurePeakGoroutines(func() {
+		processed = runPooledJobs(workers, jobs, work)
+	})
+
+	if processed != jobs {
+		t.Fatalf("processed %d jobs, want %d", processed, jobs)
+	}
+	// The workers, and the goroutine of runPooled submitting the jobs.
+	if peak > workers+1 {
+		t.Fatalf("peak of %d goroutines, want at most %d", peak, workers+1)
+	}
+}
+
+// BenchmarkPeakGoroutines runs 10000 short CPU-bound jobs, with a goroutine per job and with a pool,
+// and reports the highest number of goroutines running at once along with the time per job.
+// The pool never has more than its workers and the submitter. Goroutines per job are started faster than
+// they complete, so thousands of them are alive at once: they all hold a stack, and if the jobs held
+// a connection or a buffer, they would all hold one too. Both spend about the same time per job,
+// dominated by the job itself, but the peak of the goroutines per job approaches the number of jobs.
+func BenchmarkPeakGoroutines(b *testing.B) {
+	const jobs = 10000
+	work := func(v int) int { return int(mix(int64(v))) }
+
+	for _, testcase := range []struct {
+		name string
+		run  func() int
+	}{
+		{"per-job", func() int { return runPerGoroutine(jobs, work) }},
+		{"pool", func() int { return runPooledJobs(runtime.GOMAXPROCS(0), jobs, work) }},
+	} {
+		b.Run(testcase.name, func(b *testing.B) {
+			peak := 0
+			for i := 0; i < b.N; i++ {
+				processed := 0
+				peak = max(peak, MeasurePeakGoroutines(func() {
+					processed = testcase.run()
+				}))
+				if processed != jobs {
+					b.Fatalf("processed %d jobs, want %d", processed, jobs)
+				}
+			}
+			b.ReportMetric(float64(peak), "peak-goroutines")
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*jobs), "ns/job")
+		})
+	}
+}
+
+func TestPriorityWorkerPool(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool := NewPriorityWorkerPool(1, 10, func(v int) int {
+		if v == 0 {
+			// Keep the only worker busy until every other job is queued.
+			close(started)
+			<-release
+		}
+		return v
+	})
+
+	var got []int
+	done := make(chan struct{})
+	go func() {
+		for output := range pool.Results() {
+			got = append(got, output)
+		}
+		close(done)
+	}()
+
+	pool.Submit(0, 0)
+	<-started
+	pool.Submit(1, 1)
+	pool.Submit(2, 5)
+	pool.Submit(3, 3)
+	pool.Submit(4, 5)
+	close(release)
+
+	pool.Close()
+	<-done
+
+	want := []int{0, 2, 4, 3, 1}
+	if !slices.Equal(got, want) {
+		t.Fatalf("outputs = %v, want %v", got, want)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestPriorityWorkerPoolZeroQueueSize(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("NewPriorityWorkerPool() with a queueSize of 0 didn't panic")
+		}
+	}()
+	NewPriorityWorkerPool(1, 0, func(v int) int { return v })
+}
+
+func TestWorkerPoolCloseTimeout(t *testing.T) {
+	t.Run("Drained", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+
+		pool := NewWorkerPool(2, func(v int) int { return v })
+		done := make(chan struct{})
+		go func() {
+			for range pool.Results() {
+			}
+			close(done)
+		}()
+		for i := 0; i < 10; i++ {
+			pool.Submit(i)
+		}
+
+		if err := pool.CloseTimeout(time.Second); err != nil {
+			t.Fatalf("CloseTimeout() = %v, want nil", err)
+		}
+		<-done
+		waitForGoroutines(t, baseline)
+	})
+
+	t.Run("Forced", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+
+		pool := NewWorkerPool(2, func(v int) int { return v })
+		// Nobody reads Results, so both workers get stuck delivering their outputs.
+		pool.Submit(1)
+		pool.Submit(2)
+
+		if err := pool.CloseTimeout(10 * time.Millisecond); !errors.Is(err, ErrForcedShutdown) {
+			t.Fatalf("CloseTimeout() = %v, want %v", err, ErrForcedShutdown)
+		}
+		if _, ok := <-pool.Results(); ok {
+			t.Fatal("Results is still open after CloseTimeout")
+		}
+		waitForGoroutines(t, baseline)
+	})
+
+	t.Run("HungFn", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+
+		release := make(chan struct{})
+		pool := NewWorkerPool(1, func(v int) int {
+			<-release
+			return v
+		})
+		future := pool.SubmitFuture(1)
+
+		const d = 10 * time.Millisecond
+		start := time.Now()
+		if err := pool.CloseTimeout(d); !errors.Is(err, ErrForcedShutdown) {
+			t.Fatalf("CloseTimeout() = %v, want %v", err, ErrForcedShutdown)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("CloseTimeout() took %v with fn hung, want about %v", elapsed, 2*d)
+		}
+
+		// Once fn returns, the worker exits and Results is closed.
+		close(release)
+		if output := <-future; output != 1 {
+			t.Fatalf("output = %d, want 1", output)
+		}
+		if _, ok := <-pool.Results(); ok {
+			t.Fatal("Results is still open after the worker returned")
+		}
+		waitForGoroutines(t, baseline)
+	})
+}
+
+func TestWorkerPoolStats(t *testing.T) {
+	const workers = 4
+	const jobs = 200
+
+	pool := NewWorkerPool(workers, func(v int) int {
+		time.Sleep(100 * time.Microsecond)
+		return v
+	})
+	defer pool.Close()
+
+	stopPolling := make(chan struct{})
+	polled := make(chan error)
+	go func() {
+		for {
+			select {
+			case <-stopPolling:
+				polled <- nil
+				return
+			default:
+			}
+			stats := pool.Stats()
+			if stats.QueueLength < 0 || stats.ActiveWorkers < 0 || stats.ActiveWorkers > workers ||
+				stats.CompletedJobs > stats.SubmittedJobs {
+				polled <- fmt.Errorf("inconsistent stats: %+v", stats)
+				return
+			}
+		}
+	}()
+
+	futures := make([]<-chan int, 0, jobs)
+	for i := 0; i < jobs; i++ {
+		futures = append(futures, pool.SubmitFuture(i))
+	}
+	for _, future := range futures {
+		<-future
+	}
+	close(stopPolling)
+	if err := <-polled; err != nil {
+		t.Fatal(err)
+	}
+
+	want := PoolStats{SubmittedJobs: jobs, CompletedJobs: jobs}
+	if stats := pool.Stats(); stats != want {
+		t.Fatalf("Stats() = %+v, want %+v", stats, want)
+	}
+}